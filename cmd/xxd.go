@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -21,41 +22,141 @@ const (
     or
        xxd -r [-s offset] [-c cols] [--ps] [infile [outfile]]
 Options:
+        --addr-width   S-record address bytes: 2, 3 or 4 (S19, S28, S37). Default fits the image,
+                       or 3 when reading a pipe.
+        --addr-radix   MIF address radix: 2, 8, 10 or 16. Default 16.
+        --align        declare the -i array alignas(<n>).
     -a, --autoskip     toggle autoskip: A single '*' replaces nul-lines. Default off.
     -B, --bars         print pipes/bars before/after ASCII/EBCDIC output. Default off.
     -b, --binary       binary digit dump (incompatible with -ps, -i, -r). Default hex.
+    -C, --capitalize   capitalize -i variable names, export Go ones.
     -c, --cols         format <cols> octets per line. Default 16 (-i 12, --ps 30).
+        --const        declare the -i array and its length const.
+        --coe          output as a Xilinx .coe file of -g octet words.
+        --compat       byte-for-byte the same output as vim's xxd.
+    -d, --decimal      show offsets in decimal instead of hex.
+    -e                 little-endian dump (incompatible with -ps, -i). Default 4 octet groups.
+                       * with --readmemh, --coe, --mif or -t words: store words little-endian.
     -E, --ebcdic       show characters in EBCDIC. Default ASCII.
+        --go           output as Go source declaring a []byte.
+        --go-string    output as Go source declaring a const string.
     -g, --groups       number of octets per group in normal output. Default 2.
     -h, --help         print this summary.
+        --html         output as a self-contained HTML page, highlighting octets under the pointer.
+        --ihex         output in Intel HEX, -c sets the record length (max 255).
     -i, --include      output in C include file style.
-    -l, --length       stop after <len> octets.
+        --json         output as a JSON array of {offset, hex, chars, count} line objects.
+                       * with -a, runs of nul lines collapse to {offset, end, count, skip}.
+    -L, --lang         output as source code in <lang>: c, go, rust, python,
+                       javascript, java or csharp.
+    -l, --length       stop after <len> octets. Takes the same postfixes as -s.
+        --layout       lay hex dumps out like xxd (default), hexdump-canonical
+                       (hexdump -C) or od (od -A x -t x1z).
+        --mif          output as an Intel .mif file of -g octet words.
+    -n, --name         use <name> for the variable in -i and Go source output.
+    -o, --offset       add <off> to the displayed file position.
+        --ndjson       like --json, but an object per line (newline delimited JSON).
+        --offset-width zero pad offsets to at least <width> digits. Default 8.
+        --package      package clause of Go source output. Default main.
     -p, --ps           output in postscript plain hexdump style.
+        --readmemh     output for Verilog $readmemh, -g octets per word. Default 1.
+    -R, --color        colour octets by class: always, auto (on a terminal, unless NO_COLOR
+                       is set) or never. Default never.
+                       * -t words are coloured when their octets share a class.
+        --palette      colours as class=SGR pairs, e.g. nul=2:high=1;35. Default $XXD_COLORS.
+                       * classes: nul, printable, whitespace, control, high.
     -r, --reverse      reverse operation: convert (or patch) hexdump into ASCII output.
                        * reversing non-hexdump formats require -r<flag> (i.e. -rb, -ri, -rp).
+        --static       declare the -i array and its length static.
+        --start        give <addr> as the start address of Intel HEX or S-record output.
+        --srec         output in Motorola S-record format, -c sets the record length.
     -s, --seek         start at <seek> bytes/bits in file. Byte/bit postfixes can be used.
     		       * byte/bit postfix units are multiples of 1024.
     		       * bits (kb, mb, etc.) will be rounded down to nearest byte.
+    		       * 0x prefixed hex values are accepted, -<seek> counts from the end.
+    -t, --type         show octets as x1 (hex), o1 (octal), u1 (decimal) or d1 (signed decimal),
+                       or words like od: x2, u4, d8, o2, f4, f8, ... (-e for little-endian).
     -u, --uppercase    use upper case hex letters.
+        --verbose      with --layout hexdump-canonical or od: no '*' for repeated lines.
     -v, --version      show version.`
 	Version = `xxd v2.0 2014-17-01 by Felix Geisendörfer and Eric Lagergren`
 )
 
+// dumpTypes maps the letters of -t/--type values, named like od's, to
+// dump types. The digits after the letter give the word size.
+var dumpTypes = map[byte]int{
+	'x': xxd.DumpHex,
+	'o': xxd.DumpOctal,
+	'u': xxd.DumpDecimal,
+	'd': xxd.DumpSigned,
+	'f': xxd.DumpFloat,
+}
+
+// flagNames maps Config fields to the flags that set them, for reporting
+// validation errors
+var flagNames = map[string]string{
+	"Columns": "-c/--cols",
+	"Group":   "-g/--group",
+	"Length":  "-l/--len",
+	"Seek":    "-s/--seek",
+
+	"DisplayOffset": "-o/--offset",
+	"OffsetWidth":   "--offset-width",
+	"Align":         "--align",
+	"Package":       "--package",
+	"StartAddress":  "--start",
+	"AddressWidth":  "--addr-width",
+	"AddressRadix":  "--addr-radix",
+	"Layout":        "--layout",
+	"WordSize":      "-t/--type",
+	"HTML":          "--html",
+}
+
 func main() {
 
 	var (
+		addrWidth  = flag.Int("addr-width", 0, "S-record address bytes")
+		addrRadix  = flag.Int("addr-radix", 0, "MIF address radix")
+		align      = flag.Int("align", 0, "declare the C array alignas(n)")
 		autoskip   = flag.BoolP("autoskip", "a", false, "toggle autoskip (* replaces nul lines")
 		bars       = flag.BoolP("bars", "B", false, "print |ascii| instead of ascii")
 		binary     = flag.BoolP("binary", "b", false, "binary dump, incompatible with -ps, -i, -r")
+		capitalize = flag.BoolP("capitalize", "C", false, "capitalize C variable names")
+		coe        = flag.Bool("coe", false, "output as a Xilinx .coe file")
 		columns    = flag.IntP("cols", "c", -1, "format <cols> octets per line")
+		constant   = flag.Bool("const", false, "declare the C array const")
+		decimal    = flag.BoolP("decimal", "d", false, "show offsets in decimal")
 		ebcdic     = flag.BoolP("ebcdic", "E", false, "use EBCDIC instead of ASCII")
+		little     = flag.BoolP("little-endian", "e", false, "little-endian dump")
 		group      = flag.IntP("group", "g", -1, "num of octets per group")
+		golang     = flag.Bool("go", false, "output as Go source")
+		goString   = flag.Bool("go-string", false, "output as Go source declaring a string")
+		htmlPage   = flag.Bool("html", false, "output as an HTML page")
+		ihex       = flag.Bool("ihex", false, "output in Intel HEX")
 		cfmt       = flag.BoolP("include", "i", false, "output in C include format")
-		length     = flag.Int64P("len", "l", -1, "stop after len octets")
+		jsonDoc    = flag.Bool("json", false, "output as a JSON array of line objects")
+		ndjson     = flag.Bool("ndjson", false, "output a JSON object per line")
+		compat     = flag.Bool("compat", false, "byte-for-byte vim xxd output")
+		lang       = flag.StringP("lang", "L", "", "output as source code in lang")
+		layout     = flag.String("layout", "", "hex dump layout: xxd, hexdump-canonical or od")
+		length     = flag.StringP("len", "l", "", "stop after len octets")
+		mif        = flag.Bool("mif", false, "output as an Intel .mif file")
+		name       = flag.StringP("name", "n", "", "C variable name")
+		offset     = flag.StringP("offset", "o", "", "add off to displayed offsets")
+		offWidth   = flag.Int("offset-width", 0, "minimum digits in an offset")
+		pkg        = flag.String("package", "", "package of Go source output")
 		postscript = flag.BoolP("ps", "p", false, "output in postscript plain hd style")
+		readmemh   = flag.Bool("readmemh", false, "output for Verilog $readmemh")
 		reverse    = flag.BoolP("reverse", "r", false, "convert hex to binary")
+		color      = flag.StringP("color", "R", "never", "colour octets: always, auto or never")
+		palette    = flag.String("palette", os.Getenv("XXD_COLORS"), "colours as class=SGR pairs")
+		static     = flag.Bool("static", false, "declare the C array static")
+		srec       = flag.Bool("srec", false, "output in Motorola S-record format")
+		start      = flag.String("start", "", "Intel HEX start address")
 		seek       = flag.StringP("seek", "s", "", "start at seek bytes abs")
+		typ        = flag.StringP("type", "t", "", "octet or word format, e.g. x1, u4, f8")
 		upper      = flag.BoolP("uppercase", "u", false, "use uppercase hex letters")
+		verbose    = flag.Bool("verbose", false, "print repeated hexdump or od lines")
 		version    = flag.BoolP("version", "v", false, "print version")
 	)
 	xxdCfg := &xxd.Config{}
@@ -71,9 +172,64 @@ func main() {
 	xxdCfg.Columns = *columns
 	xxdCfg.Ebcdic = *ebcdic
 	xxdCfg.Group = *group
-	xxdCfg.Length = int(*length)
+	xxdCfg.Seek = *seek
+	xxdCfg.Decimal = *decimal
+	xxdCfg.OffsetWidth = *offWidth
+	xxdCfg.Compat = *compat
+	xxdCfg.Layout = *layout
+	xxdCfg.Verbose = *verbose
+	xxdCfg.HTML = *htmlPage
+	xxdCfg.VarName = *name
+	xxdCfg.Capitalize = *capitalize
+	xxdCfg.Static = *static
+	xxdCfg.Const = *constant
+	xxdCfg.Align = *align
+	xxdCfg.AddressWidth = *addrWidth
+	xxdCfg.AddressRadix = *addrRadix
+	xxdCfg.Package = *pkg
+	xxdCfg.GoString = *goString
+	// like xxd, offsets show the position in the file and -r -s adds the
+	// seek to the positions read back
+	xxdCfg.SeekOffsets = !*reverse
+
+	xxdCfg.Length = -1
+	if *length != "" {
+		n, err := xxd.ParseSize(*length)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if n < 0 {
+			log.Fatalf("invalid -l/--len %s: must not be negative\n", *length)
+		}
+		xxdCfg.Length = n
+	}
+
+	if *offset != "" {
+		off, err := xxd.ParseSize(*offset)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		xxdCfg.DisplayOffset = off
+	}
 	xxdCfg.Upper = *upper
 
+	xxdCfg.StartAddress = -1
+	if *start != "" {
+		addr, err := xxd.ParseSize(*start)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		xxdCfg.StartAddress = addr
+	}
+
+	if *palette != "" {
+		p, err := xxd.ParsePalette(*palette)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		xxdCfg.Palette = p
+	}
+
 	if *version {
 		fmt.Fprintln(os.Stderr, Version)
 		os.Exit(0)
@@ -83,6 +239,64 @@ func main() {
 		log.Fatalf("Too many arguments after %s\n", flag.Args()[1])
 	}
 
+	switch {
+	case *lang != "":
+		t, ok := xxd.SourceFormat(*lang)
+		if !ok {
+			log.Fatalf("invalid -L/--lang %s: not one of c, go, rust, python, javascript, java, csharp\n", *lang)
+		}
+		xxdCfg.DumpType = t
+	case *typ != "":
+		t, ok := dumpTypes[(*typ)[0]]
+		size, err := strconv.Atoi((*typ)[1:])
+		if !ok || err != nil && len(*typ) > 1 {
+			log.Fatalf("invalid -t/--type %s: not x, o, u, d or f and a size\n", *typ)
+		}
+		xxdCfg.DumpType = t
+		xxdCfg.WordSize = size
+		// -e picks the word order rather than the dump
+		xxdCfg.LittleEndian = *little
+	case *binary:
+		xxdCfg.DumpType = xxd.DumpBinary
+	case *cfmt:
+		xxdCfg.DumpType = xxd.DumpCformat
+	case *postscript:
+		xxdCfg.DumpType = xxd.DumpPostscript
+	case *readmemh, *coe, *mif:
+		switch {
+		case *readmemh:
+			xxdCfg.DumpType = xxd.DumpReadmemh
+		case *coe:
+			xxdCfg.DumpType = xxd.DumpCoe
+		default:
+			xxdCfg.DumpType = xxd.DumpMif
+		}
+		// -e picks the word order rather than the dump
+		xxdCfg.LittleEndian = *little
+	case *little:
+		xxdCfg.DumpType = xxd.DumpLittleEndian
+	case *ihex:
+		xxdCfg.DumpType = xxd.DumpIntelHex
+	case *srec:
+		xxdCfg.DumpType = xxd.DumpSRecord
+	case *golang, *goString:
+		xxdCfg.DumpType = xxd.DumpGo
+	case *jsonDoc:
+		xxdCfg.DumpType = xxd.DumpJSON
+	case *ndjson:
+		xxdCfg.DumpType = xxd.DumpNDJSON
+	default:
+		xxdCfg.DumpType = xxd.DumpHex
+	}
+
+	if err := xxdCfg.Validate(); err != nil {
+		var cfgErr *xxd.ConfigError
+		if errors.As(err, &cfgErr) && flagNames[cfgErr.Field] != "" {
+			log.Fatalf("invalid %s %v: %s\n", flagNames[cfgErr.Field], cfgErr.Value, cfgErr.Reason)
+		}
+		log.Fatalln(err)
+	}
+
 	var (
 		err  error
 		file string
@@ -97,7 +311,6 @@ func main() {
 	var inFile *os.File
 	if file == "-" {
 		inFile = os.Stdin
-		file = "stdin"
 	} else {
 		inFile, err = os.Open(file)
 		if err != nil {
@@ -106,18 +319,14 @@ func main() {
 	}
 	defer inFile.Close()
 
-	// Start *seek bytes into file
-	if *seek != "" {
-		sv := parseSeek(*seek)
-		_, err = inFile.Seek(sv, os.SEEK_SET)
-		if err != nil {
-			log.Fatalln(err)
-		}
-	}
-
 	var outFile *os.File
 	if flag.NArg() == 2 {
-		outFile, err = os.Create(flag.Args()[1])
+		if *reverse {
+			// like xxd -r, patch the outfile in place instead of truncating it
+			outFile, err = os.OpenFile(flag.Args()[1], os.O_WRONLY|os.O_CREATE, 0666)
+		} else {
+			outFile, err = os.Create(flag.Args()[1])
+		}
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -126,22 +335,27 @@ func main() {
 	}
 	defer outFile.Close()
 
-	switch {
-	case *binary:
-		xxdCfg.DumpType = xxd.DumpBinary
-	case *cfmt:
-		xxdCfg.DumpType = xxd.DumpCformat
-	case *postscript:
-		xxdCfg.DumpType = xxd.DumpPostscript
+	switch *color {
+	case "always":
+		xxdCfg.Color = true
+	case "auto":
+		// https://no-color.org
+		xxdCfg.Color = os.Getenv("NO_COLOR") == "" && isTerminal(outFile)
+	case "never":
 	default:
-		xxdCfg.DumpType = xxd.DumpHex
+		log.Fatalf("invalid -R/--color %s: not always, auto or never\n", *color)
 	}
 
 	out := bufio.NewWriter(outFile)
 	defer out.Flush()
 
 	if *reverse {
-		if err = xxd.XxdReverse(inFile, out, xxdCfg); err != nil {
+		if outFile != os.Stdout {
+			err = xxd.XxdPatch(inFile, outFile, xxdCfg)
+		} else {
+			err = xxd.XxdReverse(inFile, out, xxdCfg)
+		}
+		if err != nil {
 			log.Fatalln(err)
 		}
 		return
@@ -152,93 +366,11 @@ func main() {
 	}
 }
 
-// parses *seek input
-func parseSeek(s string) int64 {
-	var (
-		sl    = len(s)
-		split int
-	)
-
-	switch {
-	case sl >= 2:
-		if sl == 2 {
-			split = 1
-		} else {
-			split = 2
-		}
-	case sl != 0:
-		split = 0
-	default:
-		log.Fatalln("seek string somehow has len of 0")
-	}
-
-	mod := parseSpecifier(s[sl-split:])
-	ret, err := strconv.ParseFloat(s[:sl-split], 64) // 64 bit float
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	return int64(ret * mod)
-}
-
-// quick binary tree check
-// probably horribly written idk it's late at night
-func parseSpecifier(b string) float64 {
-	lb := len(b)
-	if lb == 0 {
-		return 0
-	}
-
-	var b0, b1 byte
-	if lb < 2 {
-		b0 = b[0]
-		b1 = '0'
-	} else {
-		b1 = b[1]
-		b0 = b[0]
+// isTerminal reports whether f is a terminal that understands colours
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || os.Getenv("TERM") == "dumb" {
+		return false
 	}
-
-	if b1 != '0' {
-		if b1 == 'b' { // bits, so convert bytes to bits for os.Seek()
-			if b0 == 'k' || b0 == 'K' {
-				return 0.0078125
-			}
-
-			if b0 == 'm' || b0 == 'M' {
-				return 7.62939453125e-06
-			}
-
-			if b0 == 'g' || b0 == 'G' {
-				return 7.45058059692383e-09
-			}
-		}
-
-		if b1 == 'B' { // kilo/mega/giga- bytes are assumed
-			if b0 == 'k' || b0 == 'K' {
-				return 1024
-			}
-
-			if b0 == 'm' || b0 == 'M' {
-				return 1048576
-			}
-
-			if b0 == 'g' || b0 == 'G' {
-				return 1073741824
-			}
-		}
-	} else { // kilo/mega/giga- bytes are assumed for single b, k, m, g
-		if b0 == 'k' || b0 == 'K' {
-			return 1024
-		}
-
-		if b0 == 'm' || b0 == 'M' {
-			return 1048576
-		}
-
-		if b0 == 'g' || b0 == 'G' {
-			return 1073741824
-		}
-	}
-
-	return 1 // assumes bytes as fallback
+	return fi.Mode()&os.ModeCharDevice != 0
 }
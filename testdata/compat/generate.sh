@@ -0,0 +1,30 @@
+#!/bin/sh
+# Regenerates golden.txt from the system xxd; the checked in copy was made
+# with vim's "xxd 2022-01-14 by Juergen Weigert et al.".
+#
+# Every line of cases holds xxd arguments, which are run from this
+# directory. Lines starting with -r feed the output of the last forward
+# case back through xxd -r, whose result is recorded as a -ps dump. Cases
+# xxd itself refuses are left out.
+set -e
+cd "$(dirname "$0")"
+
+out=golden.txt
+: > "$out"
+last=$(mktemp)
+trap 'rm -f "$last"' EXIT
+
+while IFS= read -r args; do
+	case "$args" in
+	-r*)
+		if res=$(eval xxd "$args" < "$last" | xxd -ps); then
+			printf '### %s\n%s\n' "$args" "$res" >> "$out"
+		fi
+		;;
+	*)
+		eval xxd "$args" > "$last"
+		printf '### %s\n' "$args" >> "$out"
+		cat "$last" >> "$out"
+		;;
+	esac
+done < cases
@@ -0,0 +1,107 @@
+package xxd
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cVarName returns the variable name xxd -i declares for the file fname,
+// or for Config.VarName when that is set. Anything that cannot appear in
+// a C identifier, such as path separators, dashes, dots and non-ASCII
+// characters, becomes '_' and a leading digit gets a '_' in front, so
+// assets/logo-v2.png becomes assets_logo_v2_png and 3d.bin _3d_bin.
+// Compat follows vim's xxd instead, which replaces every such byte and
+// prefixes "__". An empty result means stdin without -n, where like
+// xxd -i < FILE only the values are written.
+func cVarName(fname string, cfg *Config) string {
+	name := cfg.VarName
+	if name == "" && fname != "-" {
+		name = fname
+	}
+	if name == "" {
+		return ""
+	}
+
+	b := make([]byte, 0, len(name)+2)
+	if cfg.Compat {
+		if isDigit(name[0]) {
+			b = append(b, "__"...)
+		}
+		for i := 0; i < len(name); i++ {
+			if isAlnum(name[i]) {
+				b = append(b, name[i])
+			} else {
+				b = append(b, '_')
+			}
+		}
+	} else {
+		if isDigit(name[0]) {
+			b = append(b, '_')
+		}
+		for i := 0; i < len(name); {
+			r, n := utf8.DecodeRuneInString(name[i:])
+			if r < utf8.RuneSelf && (isAlnum(byte(r)) || r == '_') {
+				b = append(b, byte(r))
+			} else {
+				b = append(b, '_')
+			}
+			i += n
+		}
+	}
+
+	if cfg.Capitalize {
+		return strings.ToUpper(string(b))
+	}
+	return string(b)
+}
+
+// appendCHeader renders the opening line of the array declaration, e.g.
+// alignas(16) static const unsigned char logo_png[] = {
+func (e *encoder) appendCHeader(dst []byte, name string) []byte {
+	if e.cfg.Align > 0 {
+		dst = append(dst, "alignas("...)
+		dst = strconv.AppendInt(dst, int64(e.cfg.Align), 10)
+		dst = append(dst, ") "...)
+	}
+	dst = e.appendCQualifiers(dst)
+	dst = append(dst, unsignedChar...)
+	dst = append(dst, name...)
+	dst = append(dst, brackets...)
+	return append(dst, newLine...)
+}
+
+// appendCFooter closes the array and declares its length, e.g.
+// };
+// unsigned int logo_png_len = 146;
+func (e *encoder) appendCFooter(dst []byte, name string, n int64) []byte {
+	dst = append(dst, closeBrace...)
+	dst = e.appendCQualifiers(dst)
+	dst = append(dst, unsignedInt...)
+	dst = append(dst, name...)
+	if e.cfg.Capitalize {
+		dst = append(dst, strings.ToUpper(string(lenEquals))...)
+	} else {
+		dst = append(dst, lenEquals...)
+	}
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, semiColonNl...)
+}
+
+func (e *encoder) appendCQualifiers(dst []byte) []byte {
+	if e.cfg.Static {
+		dst = append(dst, "static "...)
+	}
+	if e.cfg.Const {
+		dst = append(dst, "const "...)
+	}
+	return dst
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,210 @@
+package xxd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Palette holds the SGR parameters, e.g. "1;32" for bold green, that each
+// class of octet is coloured with when Config.Color is set. Empty ones
+// take DefaultPalette's.
+type Palette struct {
+	Nul        string // 0x00
+	Printable  string // 0x21-0x7e
+	Whitespace string // space, \t, \n, \v, \f and \r
+	Control    string // other octets below 0x20, and 0x7f
+	High       string // 0x80-0xff
+}
+
+// DefaultPalette colours octets much like vim's xxd -R
+var DefaultPalette = Palette{
+	Nul:        "1;37",
+	Printable:  "1;32",
+	Whitespace: "1;33",
+	Control:    "1;31",
+	High:       "1;34",
+}
+
+// octet classes, 0 being no colour at all
+const (
+	classNul = iota + 1
+	classPrintable
+	classWhitespace
+	classControl
+	classHigh
+)
+
+// octetClass returns the class v is coloured by. It is that of the octet
+// itself, not of what the character column shows for it.
+func octetClass(v byte) int {
+	switch {
+	case v == 0:
+		return classNul
+	case v == ' ' || v >= '\t' && v <= '\r':
+		return classWhitespace
+	case v < 0x20 || v == 0x7f:
+		return classControl
+	case v >= 0x80:
+		return classHigh
+	}
+	return classPrintable
+}
+
+// sgr returns the parameters class is coloured with
+func (p *Palette) sgr(class int) string {
+	var s, def string
+	switch class {
+	case classNul:
+		s, def = p.Nul, DefaultPalette.Nul
+	case classPrintable:
+		s, def = p.Printable, DefaultPalette.Printable
+	case classWhitespace:
+		s, def = p.Whitespace, DefaultPalette.Whitespace
+	case classControl:
+		s, def = p.Control, DefaultPalette.Control
+	default:
+		s, def = p.High, DefaultPalette.High
+	}
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// validSGR reports whether s is made of SGR parameters only
+func validSGR(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) && s[i] != ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePalette parses a palette written like GREP_COLORS, a ':' separated
+// list of class=SGR pairs, e.g. "nul=2:high=1;35". The classes are nul,
+// printable, whitespace, control and high; those left out are empty.
+func ParsePalette(s string) (Palette, error) {
+	var p Palette
+	for _, kv := range strings.Split(s, ":") {
+		if kv == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		if !validSGR(v) {
+			return Palette{}, fmt.Errorf("xxd: invalid palette %q: %q is not SGR parameters", s, v)
+		}
+		switch k {
+		case "nul":
+			p.Nul = v
+		case "printable":
+			p.Printable = v
+		case "whitespace":
+			p.Whitespace = v
+		case "control":
+			p.Control = v
+		case "high":
+			p.High = v
+		default:
+			return Palette{}, fmt.Errorf("xxd: invalid palette %q: unknown class %q", s, k)
+		}
+	}
+	return p, nil
+}
+
+// beginOctet starts the markup of octet v, shown at offset off: an HTML
+// span, or with Config.Color the colour of v's class unless *cur, the
+// class last switched to, already is it
+func (e *encoder) beginOctet(dst []byte, v byte, off int64, cur *int) []byte {
+	if e.html {
+		return e.appendSpan(dst, v, off)
+	}
+	if !e.color {
+		return dst
+	}
+	return e.beginClass(dst, octetClass(v), cur)
+}
+
+// beginClass switches the colour to that of class c unless *cur already
+// is it; class 0 resets the colour
+func (e *encoder) beginClass(dst []byte, c int, cur *int) []byte {
+	switch {
+	case c == *cur:
+		return dst
+	case c == 0:
+		return e.endColumn(dst, cur)
+	}
+	*cur = c
+	dst = append(dst, "\x1b["...)
+	dst = append(dst, e.cfg.Palette.sgr(c)...)
+	return append(dst, 'm')
+}
+
+// wordClass returns the class the octets in b share, 0 when they differ
+func wordClass(b []byte) int {
+	c := octetClass(b[0])
+	for _, v := range b[1:] {
+		if octetClass(v) != c {
+			return 0
+		}
+	}
+	return c
+}
+
+// endOctet ends the markup beginOctet started, for HTML
+func (e *encoder) endOctet(dst []byte) []byte {
+	if e.html {
+		return append(dst, "</span>"...)
+	}
+	return dst
+}
+
+// endColumn resets the colour after a run of octets
+func (e *encoder) endColumn(dst []byte, cur *int) []byte {
+	if *cur == 0 {
+		return dst
+	}
+	*cur = 0
+	return append(dst, "\x1b[0m"...)
+}
+
+// visibleLen is the number of columns b takes on a terminal or a page,
+// i.e. its length without the escape sequences or tags of beginOctet and
+// with entities counted as one
+func (e *encoder) visibleLen(b []byte) int {
+	n := 0
+	for i := 0; i < len(b); i++ {
+		var end byte
+		switch {
+		case b[i] == 0x1b:
+			end = 'm'
+		case e.html && b[i] == '<':
+			end = '>'
+		case e.html && b[i] == '&':
+			end, n = ';', n+1
+		default:
+			n++
+			continue
+		}
+		for i < len(b) && b[i] != end {
+			i++
+		}
+	}
+	return n
+}
+
+// stripColor removes the escape sequences of a coloured dump line in
+// place, so it reverses like any other
+func stripColor(line []byte) []byte {
+	out := line[:0]
+	for i := 0; i < len(line); i++ {
+		if line[i] == 0x1b && i+1 < len(line) && line[i+1] == '[' {
+			for i < len(line) && line[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		out = append(out, line[i])
+	}
+	return out
+}
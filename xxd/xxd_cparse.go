@@ -0,0 +1,222 @@
+package xxd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// where cParser is in a C include file
+const (
+	cDecl   = iota // before the array's '{'
+	cValues        // between '{' and '}'
+	cAfter         // past the '}'
+)
+
+// cParser tokenizes C include files for the Decoder, one line at a time.
+// Besides the output of xxd -i it takes hand-edited variants: comments,
+// preprocessor lines, decimal, octal and unpadded hex literals, integer
+// suffixes and trailing commas. Input without a declaration, as written by
+// xxd -i < FILE, is taken as a bare list of values.
+type cParser struct {
+	state   int
+	comment bool   // inside /* */
+	decl    bool   // saw part of a declaration since the last ';'
+	nest    int    // depth of [] and (), where numbers are sizes or alignments
+	comma   bool   // a ',' is due before the next value
+	lenName []byte // the NAME_len identifier last seen, nil if not
+	assign  bool   // lenName was followed by '='
+
+	count   int64 // values in the array
+	length  int64 // value of NAME_len, -1 until seen
+	name    string
+	lenLine int
+}
+
+func newCParser() *cParser {
+	return &cParser{length: -1}
+}
+
+// decode appends the values on line, the n-th line of the input, to dst
+func (c *cParser) decode(dst, line []byte, n int) ([]byte, error) {
+	for i := 0; i < len(line); {
+		if c.comment {
+			k := bytes.Index(line[i:], []byte("*/"))
+			if k < 0 {
+				return dst, nil
+			}
+			c.comment = false
+			i += k + 2
+			continue
+		}
+
+		ch := line[i]
+		switch {
+		case isSpace(ch) || ch == '\n' || ch == '\r':
+			i++
+		case bytes.HasPrefix(line[i:], []byte("/*")):
+			c.comment = true
+			i += 2
+		case bytes.HasPrefix(line[i:], []byte("//")), ch == '#':
+			return dst, nil
+		case isAlnum(ch) || ch == '_':
+			j := i + 1
+			for j < len(line) && (isAlnum(line[j]) || line[j] == '_') {
+				j++
+			}
+			var err error
+			if isDigit(ch) {
+				dst, err = c.number(dst, line[i:j], n)
+			} else {
+				err = c.ident(line[i:j], n)
+			}
+			if err != nil {
+				return dst, err
+			}
+			i = j
+		default:
+			if err := c.punct(ch, n); err != nil {
+				return dst, err
+			}
+			i++
+		}
+	}
+	return dst, nil
+}
+
+func (c *cParser) ident(tok []byte, n int) error {
+	if c.state == cValues {
+		return &DecodeError{n, fmt.Sprintf("unexpected %q in array", tok)}
+	}
+	c.decl = true
+	c.assign = false
+	c.lenName = nil
+	if bytes.HasSuffix(tok, []byte("_len")) || bytes.HasSuffix(tok, []byte("_LEN")) {
+		c.lenName = tok
+	}
+	return nil
+}
+
+func (c *cParser) number(dst, tok []byte, n int) ([]byte, error) {
+	v, ok := parseCInt(tok)
+	if !ok {
+		return dst, &DecodeError{n, fmt.Sprintf("bad number %q", tok)}
+	}
+
+	switch {
+	case c.state == cDecl && !c.decl:
+		// xxd -i < FILE, values without a declaration
+		c.state = cValues
+	case c.assign:
+		c.name = string(c.lenName[:len(c.lenName)-4])
+		c.length = int64(v)
+		c.lenLine = n
+		c.assign, c.lenName = false, nil
+		return dst, nil
+	case c.state != cValues:
+		if c.nest > 0 {
+			return dst, nil
+		}
+		return dst, &DecodeError{n, fmt.Sprintf("unexpected %q outside the array", tok)}
+	}
+
+	if c.comma {
+		return dst, &DecodeError{n, fmt.Sprintf("missing ',' before %q", tok)}
+	}
+	if v > 0xff {
+		return dst, &DecodeError{n, fmt.Sprintf("%q does not fit in an unsigned char", tok)}
+	}
+	c.comma = true
+	c.count++
+	return append(dst, byte(v)), nil
+}
+
+// parseCInt parses a C integer literal: decimal, 0x hex or 0 octal,
+// optionally with a u and an l or ll suffix. Go's 0b, 0o and _ are not C.
+func parseCInt(tok []byte) (uint64, bool) {
+	digits := bytes.TrimRight(tok, "uUlL")
+	if !cIntSuffix(string(tok[len(digits):])) {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case len(digits) > 1 && digits[0] == '0' && (digits[1] == 'x' || digits[1] == 'X'):
+		base, digits = 16, digits[2:]
+	case len(digits) > 1 && digits[0] == '0':
+		base, digits = 8, digits[1:]
+	}
+	if len(digits) == 0 {
+		return 0, false
+	}
+	for _, ch := range digits {
+		v, ok := fromHexChar(ch)
+		if !ok || int(v) >= base {
+			return 0, false
+		}
+	}
+	v, err := strconv.ParseUint(string(digits), base, 64)
+	return v, err == nil
+}
+
+// cIntSuffix reports whether s is a C integer suffix: u, l or ll, in
+// either case, or u together with l or ll in either order
+func cIntSuffix(s string) bool {
+	for _, u := range []string{"", "u", "U"} {
+		for _, l := range []string{"", "l", "L", "ll", "LL"} {
+			if s == u+l || s == l+u {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *cParser) punct(ch byte, n int) error {
+	if c.state == cValues {
+		switch ch {
+		case ',':
+			if !c.comma {
+				return &DecodeError{n, "unexpected ','"}
+			}
+			c.comma = false
+		case '}':
+			c.state = cAfter
+		default:
+			return &DecodeError{n, fmt.Sprintf("unexpected %q in array", ch)}
+		}
+		return nil
+	}
+
+	switch ch {
+	case '{':
+		if c.state == cAfter {
+			return &DecodeError{n, "more than one array"}
+		}
+		c.state = cValues
+	case '[', '(':
+		c.nest++
+	case ']', ')':
+		c.nest--
+	case '=':
+		c.assign = c.lenName != nil
+	case ';':
+		c.decl, c.assign, c.lenName = false, false, nil
+	}
+	c.decl = c.decl || ch != ';'
+	return nil
+}
+
+// end checks what was parsed once the input is exhausted at line n
+func (c *cParser) end(n int) error {
+	if c.comment {
+		return &DecodeError{n, "unterminated comment"}
+	}
+	if c.state == cValues && c.decl {
+		return &DecodeError{n, "array is missing its '}'"}
+	}
+	if c.length >= 0 && c.length != c.count {
+		return &DecodeError{c.lenLine, fmt.Sprintf("%s_len is %d but the array holds %d values", c.name, c.length, c.count)}
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package xxd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// DecodeError reports input a Decoder cannot make sense of
+type DecodeError struct {
+	Line   int // 1-based line number in the dump
+	Reason string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("xxd: line %d: %s", e.Line, e.Reason)
+}
+
+// Decoder is an io.Reader that turns a hex, binary, octal, decimal, JSON,
+// C include, Go or postscript dump back into the bytes it was made from.
+// Input is parsed a line at a time as Read is called, so a Decoder
+// composes with io.Copy, gzip, bufio.Scanner and friends without holding
+// the decoded payload in memory.
+//
+// C include files are tokenized rather than scanned for hex, and the
+// array must hold as many values as its NAME_len says; malformed input is
+// reported as a *DecodeError once the octets before it have been read.
+//
+// JSON dumps are read object by object, however they are spread over
+// lines; a skip object is filled with zeros like an autoskip.
+//
+// The colours of a Config.Color dump are ignored.
+//
+// Word views come back word by word; a NaN shows no payload, so all of
+// them come back as the one math.NaN returns.
+//
+// hexdump -C and od dumps, see Config.Layout, have the line before a '*'
+// repeated up to the offset that follows it.
+//
+// Like xxd -r writing to a pipe, a line whose offset lies beyond the
+// octets decoded so far (e.g. after an autoskip '*') is preceded by zeros
+// up to that offset, and one whose offset lies before them is a
+// *DecodeError. Use XxdPatch to honour offsets in both directions.
+type Decoder struct {
+	r        *bufio.Reader
+	e        *encoder // layout the dump was written with
+	dumpType int
+	cols     int
+	base     int64    // subtracted from line offsets to get positions
+	c        *cParser // tokenizer state of C include dumps
+	ihex     *ihexParser
+	srec     *srecParser
+	src      *sourceParser // Rust, Python and other SourceEmitter dumps
+	layout   *layoutParser // hexdump -C and od dumps
+	json     *jsonParser   // JSON and NDJSON dumps
+	n        int           // number of the current line
+
+	line []byte // the dump line being parsed
+	out  []byte // decoded octets of the line
+	buf  []byte // the part of out not yet returned by Read
+	pos  int64  // number of octets returned by Read
+	left int64  // octets still to be returned, -1 when unlimited
+	gap  int64  // zeros to return before buf
+	err  error
+}
+
+// NewDecoder returns a Decoder reading a dump of cfg.DumpType from r. A
+// Columns value other than -1 caps the number of octets taken from each
+// line, as with xxd -r -c, and a Length other than -1 ends the output
+// after that many octets. If cfg does not pass Validate, Read returns the
+// validation error.
+func NewDecoder(r io.Reader, cfg *Config) *Decoder {
+	d := &Decoder{
+		r:        bufio.NewReader(r),
+		dumpType: cfg.DumpType,
+		cols:     cfg.Columns,
+		left:     cfg.Length,
+		err:      cfg.Validate(),
+	}
+	if d.err == nil && cfg.HTML {
+		d.err = &ConfigError{"HTML", cfg.HTML, "HTML pages are not read back"}
+	}
+	if d.err == nil {
+		d.e = newEncoder(cfg)
+		d.base = reverseBase(cfg)
+		switch d.dumpType {
+		case DumpCformat:
+			d.c = newCParser()
+		case DumpIntelHex:
+			d.ihex = &ihexParser{}
+		case DumpSRecord:
+			d.srec = &srecParser{}
+		}
+		if d.e.src != nil {
+			d.src = newSourceParser(d.e.src)
+		}
+		if d.e.layout != "" {
+			d.layout = newLayoutParser(d.e.layout)
+		}
+		if isJSON(d.dumpType) {
+			d.json, d.r = newJSONParser(r)
+		}
+	}
+	return d
+}
+
+// reverseBase returns what has to be subtracted from the offsets in a dump
+// made with cfg to get back positions in the input. That undoes
+// DisplayOffset and, when the offsets did not already include it, adds
+// Seek the way xxd -r -s does; a negative Seek moves positions back.
+func reverseBase(cfg *Config) int64 {
+	base := cfg.DisplayOffset
+	if cfg.Seek != "" && !cfg.SeekOffsets {
+		off, _ := ParseSize(cfg.Seek)
+		base -= off
+	}
+	return base
+}
+
+func (d *Decoder) Read(p []byte) (int, error) {
+	if d.left == 0 {
+		return 0, io.EOF
+	}
+	if d.left > 0 && int64(len(p)) > d.left {
+		p = p[:d.left]
+	}
+
+	for d.gap == 0 && len(d.buf) == 0 {
+		off, ok, b, err := d.next()
+		if err != nil {
+			return 0, err
+		}
+		if ok && off < d.pos {
+			// like xxd -r writing to a pipe, which cannot seek backwards
+			d.err = &DecodeError{d.n, fmt.Sprintf("offset %#x lies before the %#x octets already decoded, XxdPatch can write out of order", off+d.base, d.pos+d.base)}
+			return 0, d.err
+		}
+		if ok && off > d.pos {
+			d.gap = off - d.pos
+		}
+		d.buf = b
+	}
+
+	var n int
+	if d.gap > 0 {
+		n = len(p)
+		if int64(n) > d.gap {
+			n = int(d.gap)
+		}
+		for i := range p[:n] {
+			p[i] = 0
+		}
+		d.gap -= int64(n)
+	} else {
+		n = copy(p, d.buf)
+		d.buf = d.buf[n:]
+	}
+	d.pos += int64(n)
+	if d.left > 0 {
+		d.left -= int64(n)
+	}
+	return n, nil
+}
+
+// next decodes the next line of the dump. ok reports whether the line
+// carried an offset. The returned slice is only valid until the next call.
+func (d *Decoder) next() (off int64, ok bool, b []byte, err error) {
+	if d.json != nil {
+		return d.nextJSON()
+	}
+	for {
+		if d.err != nil {
+			return 0, false, nil, d.err
+		}
+		if d.err = d.readLine(); d.err != nil && len(d.line) == 0 {
+			if d.err == io.EOF {
+				if err := d.end(); err != nil {
+					d.err = err
+				}
+			}
+			continue
+		}
+
+		if hasOffsets(d.dumpType) && bytes.IndexByte(d.line, 0x1b) >= 0 {
+			d.line = stripColor(d.line)
+		}
+
+		// octets decoded before an error are returned first
+		off, d.out, ok, err = d.decodeLine(d.out[:0], d.line)
+		if err == nil && d.err == io.EOF {
+			err = d.end()
+		}
+		if err != nil {
+			d.err = err
+		}
+		return off, ok, d.out, nil
+	}
+}
+
+// nextJSON decodes the next object of a JSON dump like next does a line
+func (d *Decoder) nextJSON() (off int64, ok bool, b []byte, err error) {
+	for {
+		if d.err != nil {
+			return 0, false, nil, d.err
+		}
+		off, d.out, ok, d.err = d.json.decode(d.out[:0])
+		d.n = d.json.last
+		if ok || len(d.out) > 0 {
+			return off - d.base, ok, d.out, nil
+		}
+	}
+}
+
+// end checks the dump is complete once all of it has been read
+func (d *Decoder) end() error {
+	if d.c != nil {
+		return d.c.end(d.n)
+	}
+	return nil
+}
+
+// readLine reads the next line, of any length, into d.line
+func (d *Decoder) readLine() error {
+	d.line = d.line[:0]
+	for {
+		b, err := d.r.ReadSlice('\n')
+		d.line = append(d.line, b...)
+		if err != bufio.ErrBufferFull {
+			if len(d.line) > 0 {
+				d.n++
+			}
+			return err
+		}
+	}
+}
+
+// decodeLine appends the octets in line to dst. For hex and binary dumps
+// it also returns the offset preceding the ':', for Intel HEX and
+// S-records the address of the record; ok is false when the line has none.
+func (d *Decoder) decodeLine(dst, line []byte) (off int64, out []byte, ok bool, err error) {
+	switch d.dumpType {
+	case DumpPostscript:
+		return 0, decodePostscript(dst, line), false, nil
+	case DumpCformat:
+		out, err = d.c.decode(dst, line, d.n)
+		return 0, out, false, err
+	case DumpGo:
+		return 0, decodeGo(dst, line), false, nil
+	case DumpIntelHex:
+		off, out, ok, err = d.ihex.decode(dst, line, d.n)
+		return off - d.base, out, ok, err
+	case DumpSRecord:
+		off, out, ok, err = d.srec.decode(dst, line, d.n)
+		return off - d.base, out, ok, err
+	}
+	if d.src != nil {
+		return 0, d.src.decode(dst, line), false, nil
+	}
+	if d.layout != nil {
+		off, out, ok, err = d.layout.decode(dst, line, d.n, d.cols, d.e.cfg.Decimal)
+		return off - d.base, out, ok, err
+	}
+
+	// 0000010: 6973 2069 7320 7361 7475 7264 6179 2074   is is saturday t
+	for i := 0; i < len(line); i++ {
+		if line[i] == ':' {
+			off, ok = parseOffset(line[:i], d.e.cfg.Decimal)
+			off -= d.base
+			line = line[i+1:]
+			break
+		}
+	}
+
+	if d.dumpType == DumpLittleEndian {
+		if d.e.cfg.Compat && d.e.cols%d.e.groupSize != 0 {
+			return 0, dst, false, &DecodeError{d.n, "compat -e values that end in part of a group overlap the characters, the line cannot be read back"}
+		}
+		return off, d.e.decodeLittleEndian(dst, line), ok, nil
+	}
+	if d.e.words > 0 {
+		return off, d.e.decodeWordView(dst, line), ok, nil
+	}
+
+	start := len(dst)
+	spaces := 0
+	for i := 0; i < len(line); {
+		if d.cols > 0 && len(dst)-start == d.cols {
+			break
+		}
+		if isSpace(line[i]) {
+			// two or more spaces separate the values from the characters
+			if spaces++; spaces > 1 && len(dst) > start {
+				break
+			}
+			i++
+			continue
+		}
+		spaces = 0
+
+		var k int
+		if d.dumpType == DumpBinary {
+			var v [1]byte
+			if len(line)-i < 8 || binaryDecode(v[:], line[i:i+8]) != -1 {
+				break
+			}
+			dst, k = append(dst, v[0]), 8
+		} else if d.dumpType != DumpHex {
+			if len(line)-i < d.e.octs {
+				break
+			}
+			v, ok := numberDecode(line[i:i+d.e.octs], d.dumpType)
+			if !ok {
+				break
+			}
+			dst, k = append(dst, v), d.e.octs
+		} else {
+			if len(line)-i < 2 {
+				break
+			}
+			a, ok1 := fromHexChar(line[i])
+			b, ok2 := fromHexChar(line[i+1])
+			if !ok1 || !ok2 {
+				break
+			}
+			dst, k = append(dst, a<<4|b), 2
+		}
+		i += k
+	}
+	return off, dst, ok, nil
+}
+
+// decodeLittleEndian appends the words of an xxd -e line, given the text
+// following its ':'. Short groups are right aligned so the two spaces
+// before the characters cannot be relied on; instead the values are
+// taken from the width a full line of groups occupies.
+func (e *encoder) decodeLittleEndian(dst, line []byte) []byte {
+	if len(line) > 0 && isSpace(line[0]) {
+		line = line[1:]
+	}
+	if w := e.lineWidth(); len(line) > w {
+		line = line[:w]
+	}
+
+	for len(line) > 0 {
+		i := 0
+		for i < len(line) && !isSpace(line[i]) {
+			i++
+		}
+		word := line[:i]
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		line = line[i:]
+
+		n := len(dst)
+		for k := len(word) - 2; k >= 0; k -= 2 {
+			a, ok1 := fromHexChar(word[k])
+			b, ok2 := fromHexChar(word[k+1])
+			if !ok1 || !ok2 || len(word)%2 != 0 {
+				// not a word, e.g. the character column of a short line
+				return dst[:n]
+			}
+			dst = append(dst, a<<4|b)
+		}
+	}
+	return dst
+}
+
+// parseOffset parses the hex, or decimal, offset in front of a dump
+// line's ':'
+func parseOffset(b []byte, decimal bool) (int64, bool) {
+	var off int64
+	n := 0
+	for _, c := range b {
+		if isSpace(c) {
+			continue
+		}
+		v, ok := fromHexChar(c)
+		if !ok || decimal && v > 9 {
+			return 0, false
+		}
+		if decimal {
+			off = off*10 + int64(v)
+		} else {
+			off = off<<4 | int64(v)
+		}
+		n++
+	}
+	return off, n > 0
+}
+
+// decodePostscript appends every pair of hex digits in line to dst,
+// ignoring whatever else is there
+func decodePostscript(dst, line []byte) []byte {
+	var (
+		hi   byte
+		half bool
+	)
+	for _, c := range line {
+		v, ok := fromHexChar(c)
+		if !ok {
+			continue
+		}
+		if half {
+			dst = append(dst, hi<<4|v)
+		}
+		hi, half = v, !half
+	}
+	return dst
+}
+
+// decodeHexLiterals appends the value of every 0x literal in line to dst
+func decodeHexLiterals(dst, line []byte) []byte {
+	for i := 0; i+2 < len(line); i++ {
+		if !isPrefix(line[i : i+2]) {
+			continue
+		}
+
+		var v, n byte
+		for i += 2; n < 2 && i < len(line); n, i = n+1, i+1 {
+			h, ok := fromHexChar(line[i])
+			if !ok {
+				break
+			}
+			v = v<<4 | h
+		}
+		if n > 0 {
+			dst = append(dst, v)
+		}
+		i--
+	}
+	return dst
+}
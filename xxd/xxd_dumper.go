@@ -0,0 +1,445 @@
+package xxd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrClosed is returned when writing to a Dumper that has been closed
+var ErrClosed = errors.New("xxd: write to closed Dumper")
+
+// Dumper is an io.WriteCloser that dumps everything written to it to an
+// underlying writer, like encoding/hex.Dumper but in any of the xxd
+// formats. Offset, autoskip and partial-line state are kept between calls
+// to Write, so input can be fed in as it arrives. Close flushes the
+// trailing partial line and, for DumpCformat, the _len footer; it does
+// not close the underlying writer.
+type Dumper struct {
+	w     io.Writer
+	e     *encoder
+	name  string // variable name, empty when there are no declarations
+	fname string // input file name, for the DumpGo notice and S0 record
+
+	pending  []byte // octets of the line being collected
+	line     []byte // the most recently rendered line
+	offset   int64  // displayed offset of pending[0]
+	left     int64  // octets still to be dumped, -1 when unlimited
+	count    int64  // number of octets dumped so far
+	header   bool   // set once the C declaration has been written
+	zeroSeen int    // run length of nul lines, see skipLine
+	zeroLine []byte // second line of a nul run
+	upper    int64  // upper half of the last Intel HEX linear address
+	width    int    // S-record address bytes, 0 until the first record
+	end      int64  // S-record or MIF image end if known up front, else -1
+	held     []byte // MIF input kept until Close when end is not known
+	prev     []byte // octets of the last hexdump or od line written
+	squeezed bool   // a '*' stands for the lines since prev
+	skipOff  int64  // offset of the nul lines held back from JSON output
+	skipped  int64  // octets in them
+
+	closed bool
+	err    error
+}
+
+// NewDumper returns a Dumper writing to w in the format described by cfg.
+// Offsets shown start at cfg.DisplayOffset, a Dumper does not seek.
+// Changes to cfg after the call have no effect on the Dumper. If cfg does
+// not pass Validate, every Write and Close returns the validation error.
+// A DumpCformat array is named after cfg.VarName, or data when it is
+// empty, so that its declaration and _len footer are always written.
+func NewDumper(w io.Writer, cfg *Config) *Dumper {
+	return newDumper(w, "", cfg)
+}
+
+func newDumper(w io.Writer, fname string, cfg *Config) *Dumper {
+	d := &Dumper{w: w, err: cfg.Validate()}
+	if d.err != nil {
+		return d
+	}
+	d.e = newEncoder(cfg)
+	d.left = cfg.Length
+	d.offset = cfg.DisplayOffset
+
+	switch d.e.dumpType {
+	case DumpCformat:
+		d.name = cVarName(fname, cfg)
+		if fname == "" && d.name == "" {
+			d.name = "data"
+		}
+	case DumpGo:
+		d.name = goVarName(fname, cfg)
+		d.fname = fname
+	case DumpSRecord:
+		d.fname = fname
+		d.width = cfg.AddressWidth
+		d.end = -1
+	case DumpMif:
+		d.fname = fname
+		d.end = -1
+	default:
+		if d.e.src != nil {
+			d.name = d.e.src.varName(fname, cfg)
+		}
+	}
+	if cfg.HTML {
+		d.fname = fname
+	}
+	d.pending = make([]byte, 0, d.e.cols+1)
+	return d
+}
+
+// Write dumps p. Complete lines are written out straight away, except for
+// DumpCformat which holds the last line back until it knows whether more
+// values follow. Once Config.Length octets have been dumped, further
+// input is accepted but ignored.
+func (d *Dumper) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.closed {
+		return 0, ErrClosed
+	}
+
+	n := len(p)
+	if d.left >= 0 {
+		if int64(len(p)) > d.left {
+			p = p[:d.left]
+		}
+		d.left -= int64(len(p))
+	}
+
+	if d.e.dumpType == DumpMif && d.end < 0 {
+		// the header gives the depth, so wait for all of the input
+		d.held = append(d.held, p...)
+		return n, nil
+	}
+	if err := d.feed(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// feed splits p into lines and writes the complete ones
+func (d *Dumper) feed(p []byte) error {
+	// lines are only written once this many octets are pending
+	full := d.e.cols
+	if isSource(d.e.dumpType) || d.e.dumpType == DumpCoe {
+		full++
+	}
+
+	for len(p) > 0 {
+		k := full - len(d.pending)
+		if k > len(p) {
+			k = len(p)
+		}
+		d.pending = append(d.pending, p[:k]...)
+		p = p[k:]
+
+		if len(d.pending) < full {
+			break
+		}
+		if err := d.writeLine(d.pending[:d.e.cols], false); err != nil {
+			return err
+		}
+		d.pending = append(d.pending[:0], d.pending[d.e.cols:]...)
+	}
+	return nil
+}
+
+// Close writes any pending octets and the format's trailer
+func (d *Dumper) Close() error {
+	if d.closed {
+		return d.err
+	}
+	d.closed = true
+	if d.err != nil {
+		return d.err
+	}
+
+	if d.held != nil {
+		held := d.held
+		d.held, d.end = nil, d.offset+int64(len(held))
+		if err := d.feed(held); err != nil {
+			return err
+		}
+	}
+
+	if len(d.pending) > 0 {
+		if err := d.writeLine(d.pending, true); err != nil {
+			return err
+		}
+		d.pending = d.pending[:0]
+	} else if d.e.cfg.AutoSkip && hasOffsets(d.e.dumpType) && d.e.layout == "" {
+		// last chance to flush out suppressed lines
+		if err := d.skipLine(d.line, -1); err != nil {
+			return err
+		}
+	}
+
+	return d.writeTrailer()
+}
+
+// writeTrailer writes whatever the format puts after the last line, and
+// the header too if no line has written it yet
+func (d *Dumper) writeTrailer() error {
+	if d.e.html {
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		if d.e.layout != "" {
+			if err := d.write(d.e.appendLayoutEnd(d.line[:0], d.offset)); err != nil {
+				return err
+			}
+		}
+		return d.write(d.e.appendHTMLFooter(d.line[:0]))
+	}
+	if d.e.layout != "" {
+		return d.write(d.e.appendLayoutEnd(d.line[:0], d.offset))
+	}
+
+	switch d.e.dumpType {
+	case DumpPostscript:
+		if d.e.unbroken {
+			return d.write(newLine)
+		}
+	case DumpCformat:
+		if d.name == "" {
+			return nil
+		}
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		return d.write(d.e.appendCFooter(d.line[:0], d.name, d.count))
+	case DumpGo:
+		if !d.header {
+			// no values, the header closes the declaration itself
+			return d.writeHeader()
+		}
+		if !d.e.cfg.GoString {
+			return d.write(closeBraceNl)
+		}
+	case DumpIntelHex:
+		return d.write(d.e.appendIntelHexEnd(d.line[:0]))
+	case DumpSRecord:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		if err := d.srecordWidth(0); err != nil {
+			return err
+		}
+		return d.write(d.e.appendSRecordEnd(d.line[:0], d.width))
+	case DumpCoe:
+		if !d.header {
+			if err := d.writeHeader(); err != nil {
+				return err
+			}
+			return d.write([]byte(";\n"))
+		}
+	case DumpMif:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		return d.write(d.e.appendMifEnd(d.line[:0]))
+	case DumpJSON, DumpNDJSON:
+		return d.writeJSONEnd()
+	default:
+		if d.e.src != nil {
+			if !d.header {
+				return d.writeHeader()
+			}
+			return d.write(d.e.src.appendFooter(d.line[:0], d.name, d.count))
+		}
+	}
+	return nil
+}
+
+// writeLine renders and writes the octets in b as a single line
+func (d *Dumper) writeLine(b []byte, last bool) error {
+	off := d.offset
+	d.offset += int64(len(b))
+	d.count += int64(len(b))
+
+	switch d.e.dumpType {
+	case DumpPostscript:
+		d.line = d.e.appendPostscript(d.line[:0], b)
+	case DumpCformat:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		d.line = d.e.appendCformat(d.line[:0], b, last)
+	case DumpGo:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		d.line = d.e.appendGo(d.line[:0], b, last)
+	case DumpIntelHex:
+		if off+int64(len(b)) > maxAddress {
+			d.err = ErrAddressRange
+			return d.err
+		}
+		d.line = d.e.appendIntelHex(d.line[:0], off, b, &d.upper)
+	case DumpSRecord:
+		if err := d.srecordWidth(off + int64(len(b))); err != nil {
+			return err
+		}
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		d.line = d.e.appendSRecordData(d.line[:0], off, b, d.width)
+	case DumpReadmemh:
+		d.line = d.e.appendReadmemh(d.line[:0], off, b, d.count == int64(len(b)))
+	case DumpCoe:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		d.line = d.e.appendCoe(d.line[:0], b, last)
+	case DumpMif:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		d.line = d.e.appendMif(d.line[:0], off, b, d.end)
+	case DumpJSON, DumpNDJSON:
+		return d.writeJSONLine(off, b)
+	default:
+		if err := d.writeHeader(); err != nil {
+			return err
+		}
+		if d.e.src != nil {
+			d.line = d.e.src.appendLine(d.line[:0], b, d.e.caps)
+			break
+		}
+		switch d.e.layout {
+		case LayoutHexdump:
+			d.line = d.e.appendHexdumpLine(d.line[:0], off, b)
+			return d.squeezeLine(b)
+		case LayoutOd:
+			d.line = d.e.appendOdLine(d.line[:0], off, b)
+			return d.squeezeLine(b)
+		}
+		d.line = d.e.appendLine(d.line[:0], off, b)
+		if d.e.cfg.AutoSkip {
+			// only complete lines may be skipped
+			nz := 1
+			if len(b) == d.e.cols && empty(b) {
+				nz = 0
+			}
+			return d.skipLine(d.line, nz)
+		}
+	}
+	return d.write(d.line)
+}
+
+// skipLine writes l subject to autoskip, mirroring xxdline() in vim's
+// xxd.c: the first nul line of a run is printed, a run of two prints both,
+// longer runs collapse into a single '*'. nz is 1 for a line with data, 0
+// for a nul line and -1 to flush at the end of input, where l is the last
+// line rendered.
+func (d *Dumper) skipLine(l []byte, nz int) error {
+	if nz == 0 {
+		if d.zeroSeen == 1 {
+			d.zeroLine = append(d.zeroLine[:0], l...)
+		}
+		d.zeroSeen++
+		if d.zeroSeen > 1 {
+			return nil
+		}
+		return d.write(l)
+	}
+
+	if nz < 0 {
+		d.zeroSeen--
+	}
+	if d.zeroSeen == 2 {
+		if err := d.write(d.zeroLine); err != nil {
+			return err
+		}
+	}
+	if d.zeroSeen > 2 {
+		if err := d.write(asterisk); err != nil {
+			return err
+		}
+		if err := d.write(newLine); err != nil {
+			return err
+		}
+	}
+
+	zeroSeen := d.zeroSeen
+	d.zeroSeen = 0
+	if nz < 0 && zeroSeen <= 0 {
+		return nil
+	}
+	return d.write(l)
+}
+
+// writeHeader writes what precedes the first line once, e.g. the
+// "unsigned char NAME[] = {" line. Declarations written before any value
+// is known to follow are complete ones of an empty array.
+func (d *Dumper) writeHeader() error {
+	if d.header {
+		return nil
+	}
+
+	var h []byte
+	switch d.e.dumpType {
+	case DumpHex, DumpBinary, DumpLittleEndian, DumpOctal, DumpDecimal, DumpSigned, DumpFloat:
+		if !d.e.html {
+			return nil
+		}
+		h = d.e.appendHTMLHeader(nil, d.fname)
+	case DumpCformat:
+		if d.name == "" {
+			return nil
+		}
+		h = d.e.appendCHeader(nil, d.name)
+	case DumpGo:
+		h = d.e.appendGoHeader(nil, d.fname, d.name, d.count)
+	case DumpSRecord:
+		h = d.e.appendSRecordHeader(nil, d.fname)
+	case DumpCoe:
+		h = d.e.appendCoeHeader(nil)
+	case DumpMif:
+		h = d.e.appendMifHeader(nil, d.fname, d.end)
+	default:
+		if d.e.src == nil {
+			return nil
+		}
+		h = d.e.src.appendHeader(nil, d.name, d.count)
+	}
+	d.header = true
+	return d.write(h)
+}
+
+// srecordWidth settles the S-record address width for a record ending
+// just before end. Unless Config.AddressWidth fixes it, the width is
+// picked from the image size when Xxd knows it, and is 3 bytes (S28)
+// otherwise, so that every record of a file is of the same type. It is
+// widened to fit Config.StartAddress, which the end record holds.
+func (d *Dumper) srecordWidth(end int64) error {
+	if end > maxAddress {
+		d.err = ErrAddressRange
+		return d.err
+	}
+	if d.width == 0 {
+		d.width = 3
+		if d.end >= 0 {
+			d.width = srecWidth(d.end)
+		}
+		if a := d.e.cfg.StartAddress; a >= 0 && srecWidth(a+1) > d.width {
+			d.width = srecWidth(a + 1)
+		}
+	}
+	if srecWidth(end) > d.width {
+		d.err = fmt.Errorf("xxd: address %#x does not fit in %d bytes", end-1, d.width)
+		return d.err
+	}
+	return nil
+}
+
+func (d *Dumper) write(b []byte) error {
+	if _, err := d.w.Write(b); err != nil {
+		d.err = err
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package xxd
+
+import (
+	"bytes"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// goVarName returns the Go identifier DumpGo declares for the file fname,
+// or for Config.VarName when that is set. File names become camel case
+// with the extension upper cased, so assets/logo.png becomes assetsLogoPNG;
+// stdin without a VarName is called data. Capitalize exports the name.
+func goVarName(fname string, cfg *Config) string {
+	var b []rune
+	if cfg.VarName != "" {
+		for _, r := range cfg.VarName {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				r = '_'
+			}
+			b = append(b, r)
+		}
+	} else if fname == "-" || fname == "" {
+		b = []rune("data")
+	} else {
+		ext := -1
+		if i := strings.LastIndexByte(fname, '.'); i > strings.LastIndexByte(fname, '/') {
+			ext = i
+		}
+		upper := false
+		for i, r := range fname {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = len(b) > 0
+				continue
+			}
+			switch {
+			case ext >= 0 && i > ext:
+				r = unicode.ToUpper(r)
+			case upper:
+				r = unicode.ToUpper(r)
+			}
+			upper = false
+			b = append(b, r)
+		}
+		if len(b) == 0 {
+			b = []rune("data")
+		}
+	}
+
+	if unicode.IsDigit(b[0]) {
+		b = append([]rune{'_'}, b...)
+	}
+	if cfg.Capitalize {
+		b[0] = unicode.ToUpper(b[0])
+	}
+	if token.IsKeyword(string(b)) {
+		b = append(b, '_')
+	}
+	return string(b)
+}
+
+// appendGoHeader renders the generated code notice, the package clause and
+// the opening line of the declaration, e.g.
+// var logoPNG = []byte{
+// With n set to 0 the declaration is written complete, since gofmt wants
+// an empty composite literal on a single line.
+func (e *encoder) appendGoHeader(dst []byte, fname, name string, n int64) []byte {
+	dst = append(dst, "// Code generated by xxd"...)
+	if fname != "-" && fname != "" {
+		dst = append(dst, " from "...)
+		dst = append(dst, fname...)
+	}
+	dst = append(dst, ". DO NOT EDIT.\n\npackage "...)
+	dst = append(dst, e.goPackage()...)
+	dst = append(dst, "\n\n"...)
+
+	if e.cfg.GoString {
+		dst = append(dst, "const "...)
+		dst = append(dst, name...)
+		if n == 0 {
+			return append(dst, " = \"\"\n"...)
+		}
+		return append(dst, " = \"\" +\n"...)
+	}
+
+	dst = append(dst, "var "...)
+	dst = append(dst, name...)
+	if n == 0 {
+		return append(dst, " = []byte{}\n"...)
+	}
+	return append(dst, " = []byte{\n"...)
+}
+
+// appendGo renders b as one line of the declaration, either byte slice
+// elements or, for Config.GoString, a string literal of \x escapes that is
+// concatenated with the next line unless it is the last.
+func (e *encoder) appendGo(dst []byte, b []byte, last bool) []byte {
+	var char [4]byte
+	dst = append(dst, '\t')
+	if e.cfg.GoString {
+		dst = append(dst, '"')
+		for i := 0; i < len(b); i++ {
+			cfmtEncode(char[:], b[i:i+1], e.caps)
+			char[0] = '\\'
+			dst = append(dst, char[:]...)
+		}
+		dst = append(dst, '"')
+		if !last {
+			dst = append(dst, " +"...)
+		}
+		return append(dst, newLine...)
+	}
+
+	for i := 0; i < len(b); i++ {
+		cfmtEncode(char[:], b[i:i+1], e.caps)
+		dst = append(dst, char[:]...)
+		if i != len(b)-1 {
+			dst = append(dst, commaSpace...)
+		}
+	}
+	// gofmt wants the trailing comma of a multi-line literal
+	dst = append(dst, comma...)
+	return append(dst, newLine...)
+}
+
+func (e *encoder) goPackage() string {
+	if e.cfg.Package == "" {
+		return "main"
+	}
+	return e.cfg.Package
+}
+
+// validPackage reports whether name can appear in a package clause
+func validPackage(name string) bool {
+	return name == "" || token.IsIdentifier(name) && name != "_"
+}
+
+// decodeGo appends the octets of a line of DumpGo output, taking both the
+// 0x elements of a byte slice and the \x escapes of a string. Only what
+// follows the slice's '{' or lies within quotes is read, since the file
+// name in the generated code notice, the package name and the variable
+// name may all look like hex literals.
+func decodeGo(dst, line []byte) []byte {
+	s := strings.TrimLeftFunc(string(line), unicode.IsSpace)
+	switch {
+	case strings.HasPrefix(s, "//"), strings.HasPrefix(s, "package"):
+		return dst
+	case strings.Contains(s, `"`):
+		return decodeGoString(dst, line[bytes.IndexByte(line, '"'):])
+	}
+	if i := bytes.IndexByte(line, '{'); i >= 0 {
+		return decodeHexLiterals(dst, line[i+1:])
+	}
+	if strings.HasPrefix(s, "var") || strings.HasPrefix(s, "const") {
+		return dst
+	}
+	return decodeHexLiterals(dst, line)
+}
+
+// decodeGoString appends the \x escapes in line
+func decodeGoString(dst, line []byte) []byte {
+	for i := 0; i+3 < len(line); i++ {
+		if line[i] != '\\' || line[i+1] != 'x' {
+			continue
+		}
+		a, ok1 := fromHexChar(line[i+2])
+		b, ok2 := fromHexChar(line[i+3])
+		if ok1 && ok2 {
+			dst = append(dst, a<<4|b)
+			i += 3
+		}
+	}
+	return dst
+}
@@ -0,0 +1,115 @@
+package xxd
+
+import (
+	"html"
+	"strconv"
+)
+
+// CSS classes of the octet classes, see octetClass
+var htmlClasses = [...]string{
+	classNul:        "n",
+	classPrintable:  "p",
+	classWhitespace: "w",
+	classControl:    "c",
+	classHigh:       "h",
+}
+
+// htmlHead starts the page up to the dump, its title goes in between
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>`
+
+// htmlStyle follows the title. Octets are coloured by class, and the one
+// under the pointer is highlighted in both columns.
+const htmlStyle = `</title>
+<style>
+body { background: #fff; color: #222; }
+pre { font: 13px/1.4 monospace; }
+a { color: #888; text-decoration: none; }
+a:target { background: #ff8; }
+.n { color: #aaa; }
+.p { color: #070; }
+.w { color: #a60; }
+.c { color: #c00; }
+.h { color: #00c; }
+.hl { background: #fd6; }
+</style>
+</head>
+<body>
+<pre>
+`
+
+// htmlTail ends the page after the dump
+const htmlTail = `</pre>
+<script>
+(function () {
+	var pre = document.querySelector("pre");
+	function mark(ev, on) {
+		var o = ev.target.getAttribute("data-o");
+		if (o === null) {
+			return;
+		}
+		var spans = pre.querySelectorAll('span[data-o="' + o + '"]');
+		for (var i = 0; i < spans.length; i++) {
+			spans[i].classList.toggle("hl", on);
+		}
+	}
+	pre.addEventListener("mouseover", function (ev) { mark(ev, true); });
+	pre.addEventListener("mouseout", function (ev) { mark(ev, false); });
+})();
+</script>
+</body>
+</html>
+`
+
+// appendHTMLHeader renders the start of a page titled with fname, up to
+// the opening of the dump
+func (e *encoder) appendHTMLHeader(dst []byte, fname string) []byte {
+	if fname == "-" || fname == "" {
+		fname = "stdin"
+	}
+	dst = append(dst, htmlHead...)
+	dst = append(dst, html.EscapeString("xxd "+fname)...)
+	return append(dst, htmlStyle...)
+}
+
+// appendHTMLFooter renders the end of the page, with the script doing the
+// highlighting
+func (e *encoder) appendHTMLFooter(dst []byte) []byte {
+	return append(dst, htmlTail...)
+}
+
+// appendSpan opens the span of octet v, shown at offset off. Both columns
+// give an octet the same data-o, which is what the highlighting goes by.
+func (e *encoder) appendSpan(dst []byte, v byte, off int64) []byte {
+	dst = append(dst, `<span class="`...)
+	dst = append(dst, htmlClasses[octetClass(v)]...)
+	dst = append(dst, `" data-o="`...)
+	dst = strconv.AppendInt(dst, off, 16)
+	return append(dst, `">`...)
+}
+
+// appendAnchor renders offset, the digits of a line offset, as a link to
+// itself
+func appendAnchor(dst []byte, offset string) []byte {
+	dst = append(dst, `<a id="o`...)
+	dst = append(dst, offset...)
+	dst = append(dst, `" href="#o`...)
+	dst = append(dst, offset...)
+	dst = append(dst, `">`...)
+	dst = append(dst, offset...)
+	return append(dst, "</a>"...)
+}
+
+// appendEntity renders v, one of < > &, as an HTML entity
+func appendEntity(dst []byte, v byte) []byte {
+	switch v {
+	case '<':
+		return append(dst, "&lt;"...)
+	case '>':
+		return append(dst, "&gt;"...)
+	}
+	return append(dst, "&amp;"...)
+}
@@ -0,0 +1,169 @@
+package xxd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Intel HEX record types
+const (
+	ihexData = iota
+	ihexEOF
+	ihexSegment      // extended segment address
+	ihexStartSegment // start segment address, CS:IP
+	ihexLinear       // extended linear address
+	ihexStartLinear  // start linear address, EIP
+)
+
+// ErrAddressRange is returned when octets would be placed beyond the 4 GiB
+// an Intel HEX or S-record file can address
+var ErrAddressRange = errors.New("xxd: address beyond 4 GiB")
+
+// maxAddress is one past the last address of a 32-bit image
+const maxAddress = 1 << 32
+
+// appendIntelHex renders b, to be loaded at address off, as Intel HEX data
+// records. An extended linear address record comes first whenever the
+// upper 16 bits of the address differ from *upper, which is updated, and
+// records are split where they would cross a 64 KiB boundary. Like most
+// programmers expect, digits are always upper case.
+func (e *encoder) appendIntelHex(dst []byte, off int64, b []byte, upper *int64) []byte {
+	for len(b) > 0 {
+		if hi := off >> 16; hi != *upper {
+			dst = appendIntelRecord(dst, ihexLinear, 0, []byte{byte(hi >> 8), byte(hi)})
+			*upper = hi
+		}
+		n := 0x10000 - int(off&0xffff)
+		if n > len(b) {
+			n = len(b)
+		}
+		dst = appendIntelRecord(dst, ihexData, uint16(off), b[:n])
+		off += int64(n)
+		b = b[n:]
+	}
+	return dst
+}
+
+// appendIntelHexEnd renders the start linear address record, when
+// Config.StartAddress asks for one, and the end of file record
+func (e *encoder) appendIntelHexEnd(dst []byte) []byte {
+	if a := e.cfg.StartAddress; a >= 0 {
+		dst = appendIntelRecord(dst, ihexStartLinear, 0, []byte{byte(a >> 24), byte(a >> 16), byte(a >> 8), byte(a)})
+	}
+	return appendIntelRecord(dst, ihexEOF, 0, nil)
+}
+
+// appendIntelRecord renders a single record, e.g.
+// :0B0010006164647265737320676170A7
+func appendIntelRecord(dst []byte, typ byte, addr uint16, data []byte) []byte {
+	sum := byte(len(data)) + byte(addr>>8) + byte(addr) + typ
+	dst = append(dst, ':')
+	dst = appendHexByte(dst, byte(len(data)))
+	dst = appendHexByte(dst, byte(addr>>8))
+	dst = appendHexByte(dst, byte(addr))
+	dst = appendHexByte(dst, typ)
+	for _, v := range data {
+		dst = appendHexByte(dst, v)
+		sum += v
+	}
+	dst = appendHexByte(dst, -sum)
+	return append(dst, newLine...)
+}
+
+// appendHexByte renders v as two upper case hex digits
+func appendHexByte(dst []byte, v byte) []byte {
+	return append(dst, udigits[v>>4], udigits[v&0x0f])
+}
+
+// ihexParser keeps the address state of an Intel HEX file being reversed.
+// Records out of address order are placed by XxdPatch, while the Decoder,
+// which cannot seek backwards, reports them as a *DecodeError.
+type ihexParser struct {
+	base int64 // added to record addresses, from segment or linear records
+	done bool  // seen the end of file record
+}
+
+// decode appends the data of the record on line, the n-th line of the
+// input, to dst and returns the address it belongs at. ok is false for
+// lines carrying no data.
+func (p *ihexParser) decode(dst, line []byte, n int) (off int64, out []byte, ok bool, err error) {
+	line = trimSpace(line)
+	if p.done || len(line) == 0 {
+		return 0, dst, false, nil
+	}
+	if line[0] != ':' {
+		return 0, dst, false, &DecodeError{n, "record does not start with ':'"}
+	}
+
+	start := len(dst)
+	dst, good := decodeHexPairs(dst, line[1:])
+	rec := dst[start:]
+	dst = dst[:start]
+	switch {
+	case !good:
+		return 0, dst, false, &DecodeError{n, "record is not hex digits"}
+	case len(rec) < 5 || len(rec) != int(rec[0])+5:
+		return 0, dst, false, &DecodeError{n, "record length does not match its byte count"}
+	}
+
+	var sum byte
+	for _, v := range rec {
+		sum += v
+	}
+	if sum != 0 {
+		want := rec[len(rec)-1] - sum
+		return 0, dst, false, &DecodeError{n, fmt.Sprintf("checksum is %02X, should be %02X", rec[len(rec)-1], want)}
+	}
+
+	addr := int64(rec[1])<<8 | int64(rec[2])
+	data := rec[4 : len(rec)-1]
+	switch rec[3] {
+	case ihexData:
+		return p.base + addr, append(dst, data...), true, nil
+	case ihexEOF:
+		p.done = true
+	case ihexSegment, ihexLinear:
+		if len(data) != 2 {
+			return 0, dst, false, &DecodeError{n, "address record needs 2 bytes"}
+		}
+		p.base = int64(data[0])<<8 | int64(data[1])
+		if rec[3] == ihexSegment {
+			p.base <<= 4
+		} else {
+			p.base <<= 16
+		}
+	case ihexStartSegment, ihexStartLinear:
+		// entry points have no place in a binary image
+	default:
+		return 0, dst, false, &DecodeError{n, fmt.Sprintf("unknown record type %02X", rec[3])}
+	}
+	return 0, dst, false, nil
+}
+
+// decodeHexPairs appends the octets spelled by the hex digits in s, ok is
+// false when s holds anything else or an odd number of digits
+func decodeHexPairs(dst, s []byte) ([]byte, bool) {
+	if len(s)%2 != 0 {
+		return dst, false
+	}
+	for i := 0; i < len(s); i += 2 {
+		a, ok1 := fromHexChar(s[i])
+		b, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return dst, false
+		}
+		dst = append(dst, a<<4|b)
+	}
+	return dst, true
+}
+
+// trimSpace strips leading and trailing white space, including line ends
+func trimSpace(b []byte) []byte {
+	for len(b) > 0 && (isSpace(b[0]) || b[0] == '\r' || b[0] == '\n') {
+		b = b[1:]
+	}
+	for len(b) > 0 && (isSpace(b[len(b)-1]) || b[len(b)-1] == '\r' || b[len(b)-1] == '\n') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
@@ -2,8 +2,8 @@ package xxd
 
 import (
 	"bufio"
+	"errors"
 	"io"
-	"log"
 	"strconv"
 )
 
@@ -18,316 +18,464 @@ func XxdBasic(r io.Reader, w io.Writer, xxdCfg *Config) error {
 }
 
 func Xxd(r io.Reader, w io.Writer, fname string, xxdCfg *Config) error {
-	var (
-		lineOffset int64
-		hexOffset  = make([]byte, 6)
-		groupSize  int
-		cols       int
-		octs       int
-		caps       = ldigits
-		doCHeader  = true
-		doCEnd     bool
-		// enough room for "unsigned char NAME_FORMAT[] = {"
-		varDeclChar = make([]byte, 14+len(fname)+6)
-		// enough room for "unsigned int NAME_FORMAT = "
-		varDeclInt = make([]byte, 16+len(fname)+7)
-		nulLine    int64
-		totalOcts  int
-	)
+	if err := xxdCfg.Validate(); err != nil {
+		return err
+	}
+	d := newDumper(w, fname, xxdCfg)
 
-	// Generate the first and last line in the -i output:
-	// e.g. unsigned char foo_txt[] = { and unsigned int foo_txt_len =
-	if xxdCfg.DumpType == DumpCformat {
-		// copy over "unnsigned char " and "unsigned int"
-		_ = copy(varDeclChar[0:14], unsignedChar[:])
-		_ = copy(varDeclInt[0:16], unsignedInt[:])
-
-		for i := 0; i < len(fname); i++ {
-			if fname[i] != '.' {
-				varDeclChar[14+i] = fname[i]
-				varDeclInt[16+i] = fname[i]
-			} else {
-				varDeclChar[14+i] = '_'
-				varDeclInt[16+i] = '_'
+	if xxdCfg.Seek != "" {
+		off, _ := ParseSize(xxdCfg.Seek) // checked by Validate
+		pos, err := seek(r, off)
+		if err != nil {
+			return err
+		}
+		if xxdCfg.SeekOffsets || xxdCfg.Compat {
+			d.offset += pos
+		}
+	}
+
+	if d.e.dumpType == DumpSRecord || d.e.dumpType == DumpMif {
+		// S-records pick their address width from the image size, MIF
+		// files start with their depth
+		n := xxdCfg.Length
+		if s, ok := r.(io.Seeker); ok {
+			if left, ok := remaining(s); ok && (n < 0 || left < n) {
+				n = left
 			}
 		}
-		// copy over "[] = {" and "_len = "
-		_ = copy(varDeclChar[14+len(fname):], brackets[:])
-		_ = copy(varDeclInt[16+len(fname):], lenEquals[:])
+		if n >= 0 {
+			d.end = d.offset + n
+		}
 	}
 
-	// Switch between upper- and lower-case hex chars
-	if xxdCfg.Upper {
-		caps = udigits
-	}
-
-	// xxd -bpi FILE outputs in binary format
-	// xxd -b -p -i FILE outputs in C format
-	// simply catch the last option since that's what I assume the author
-	// wanted...
-	if xxdCfg.Columns == -1 {
-		switch dumpType {
-		case DumpPostscript:
-			cols = 30
-		case DumpCformat:
-			cols = 12
-		case DumpBinary:
-			cols = 6
-		default:
-			cols = 16
+	if xxdCfg.Length != -1 {
+		r = io.LimitReader(r, xxdCfg.Length)
+	}
+
+	if _, err := io.Copy(d, bufio.NewReader(r)); err != nil {
+		return err
+	}
+	return d.Close()
+}
+
+// seek skips to off in r, or to -off before its end when off is negative,
+// and returns the resulting position. Readers that are not io.Seekers, or
+// whose Seek fails as it does for pipes, are read and discarded up to off.
+func seek(r io.Reader, off int64) (int64, error) {
+	if s, ok := r.(io.Seeker); ok {
+		whence := io.SeekStart
+		if off < 0 {
+			whence = io.SeekEnd
+		}
+		if pos, err := s.Seek(off, whence); err == nil || off < 0 {
+			return pos, err
 		}
-	} else {
-		cols = xxdCfg.Columns
 	}
 
-	// See above comment
-	switch dumpType {
+	if off < 0 {
+		return 0, errors.New("xxd: seeking from the end needs a seekable input")
+	}
+	n, err := io.CopyN(io.Discard, r, off)
+	if err == io.EOF {
+		// seeking past the end just dumps nothing
+		return n, nil
+	}
+	return n, err
+}
+
+// encoder carries the per-call dump state. Everything in it is resolved
+// from the Config handed to Xxd or XxdReverse, so concurrent dumps with
+// different settings never see each other's formats.
+type encoder struct {
+	cfg       Config
+	dumpType  int
+	cols      int
+	octs      int
+	groupSize int
+	caps      string
+	src       *SourceEmitter // language of source output other than C and Go
+	layout    string         // LayoutHexdump or LayoutOd, empty for xxd's
+	words     int            // octets per word of a word view, 0 for none
+	color     bool           // colour octets by class, see Palette
+	html      bool           // mark the line up for an HTML page
+	unbroken  bool           // a postscript dump of a single line
+}
+
+// newEncoder resolves columns, octets-per-byte and grouping for cfg.
+//
+// xxd -bpi FILE outputs in binary format
+// xxd -b -p -i FILE outputs in C format
+// the caller is expected to pick a single DumpType, the CLI simply catches
+// the last option since that's what I assume the author wanted...
+func newEncoder(cfg *Config) *encoder {
+	e := &encoder{cfg: *cfg, dumpType: cfg.DumpType, caps: ldigits, src: sourceEmitter(cfg.DumpType)}
+
+	// Switch between upper- and lower-case hex chars
+	if cfg.Upper {
+		e.caps = udigits
+	}
+
+	switch {
+	case cfg.Columns == -1:
+		e.cols = defaultColumns(e.dumpType)
+	case cfg.Columns == 0 && cfg.Compat:
+		// vim's xxd takes -c 0 as the default, or no line breaks for -ps
+		e.cols = defaultColumns(e.dumpType)
+		e.unbroken = e.dumpType == DumpPostscript
+	default:
+		e.cols = cfg.Columns
+	}
+
+	switch e.dumpType {
 	case DumpBinary:
-		octs = 8
-		groupSize = 1
+		e.octs = 8
+		e.groupSize = 1
+	case DumpOctal, DumpDecimal:
+		e.octs = 3
+		e.groupSize = 1
+	case DumpSigned:
+		e.octs = 4
+		e.groupSize = 1
 	case DumpPostscript:
-		octs = 0
-	case DumpCformat:
-		octs = 4
+		e.octs = 2
+	case DumpCformat, DumpGo:
+		e.octs = 4
+	case DumpReadmemh, DumpCoe, DumpMif:
+		e.octs = 2
+		e.groupSize = 1
+	case DumpLittleEndian:
+		e.octs = 2
+		e.groupSize = 4
 	default:
-		octs = 2
-		groupSize = 2
+		e.octs = 2
+		e.groupSize = 2
+	}
+	if e.src != nil {
+		// every language is laid out like C
+		e.octs = 4
+		e.groupSize = 0
 	}
 
-	if xxdCfg.Group != -1 {
-		groupSize = xxdCfg.Group
+	if cfg.Layout != LayoutXxd {
+		e.layout = cfg.Layout
+	}
+	if e.layout == LayoutHexdump {
+		e.groupSize = 8
 	}
 
-	// If -l is smaller than the number of cols just truncate the cols
-	if xxdCfg.Length != -1 {
-		if xxdCfg.Length < int(cols) {
-			cols = int(xxdCfg.Length)
+	if cfg.Group != -1 {
+		e.groupSize = cfg.Group
+	}
+
+	switch {
+	case cfg.WordSize > 1:
+		e.words = cfg.WordSize
+	case e.dumpType == DumpFloat:
+		e.words = 8
+	}
+	if e.words > 0 {
+		e.groupSize = e.words
+	}
+
+	// only lines with a character column are coloured, pages are styled
+	// instead
+	e.html = cfg.HTML
+	e.color = cfg.Color && !cfg.HTML && hasOffsets(e.dumpType)
+
+	// memory files default to a word per line, or as many as fit in 16
+	// octets for $readmemh
+	if isMemInit(e.dumpType) && cfg.Columns == -1 {
+		if e.dumpType == DumpReadmemh && e.groupSize < e.cols {
+			e.cols -= e.cols % e.groupSize
+		} else {
+			e.cols = e.groupSize
 		}
 	}
 
-	if octs < 1 {
-		octs = cols
+	if e.octs < 1 {
+		e.octs = e.cols
 	}
+	return e
+}
 
-	// These are bumped down from the beginning of the function in order to
-	// allow for their sizes to be allocated based on the user's specification
-	var (
-		line = make([]byte, cols)
-		char = make([]byte, octs)
-	)
+// hasOffsets reports whether dumpType writes lines starting with an
+// offset and ending in a character column, the ones autoskip and patching
+// apply to
+func hasOffsets(dumpType int) bool {
+	switch dumpType {
+	case DumpHex, DumpBinary, DumpLittleEndian, DumpOctal, DumpDecimal, DumpSigned, DumpFloat:
+		return true
+	}
+	return false
+}
 
-	c := int64(0) // number of characters
-	nl := int64(0)
-	r = bufio.NewReader(r)
+// defaultColumns is the number of octets per line used when -c is not
+// given
+func defaultColumns(dumpType int) int {
+	switch {
+	case dumpType == DumpPostscript:
+		return 30
+	case isSource(dumpType):
+		return 12
+	case dumpType == DumpBinary:
+		return 6
+	default:
+		return 16
+	}
+}
 
+// hexWidth is the number of columns n encoded bytes occupy in a hex or
+// binary line, including the space written after every complete group
+func (e *encoder) hexWidth(n int) int {
+	if e.words > 0 {
+		return (n + e.words - 1) / e.words * (e.wordWidth() + 1)
+	}
+	if e.groupSize <= 0 {
+		return n * e.octs
+	}
+	if e.dumpType == DumpLittleEndian {
+		// short groups are right aligned to the full group width
+		return (n + e.groupSize - 1) / e.groupSize * (e.groupSize*e.octs + 1)
+	}
+	return n*e.octs + n/e.groupSize
+}
+
+// lineWidth is the width of the values of a full line. vim's xxd gives a
+// short last group the trailing space of a complete one and treats -g 0
+// as a single group.
+func (e *encoder) lineWidth() int {
+	if e.cfg.Compat && e.dumpType != DumpLittleEndian {
+		g := e.groupSize
+		if g <= 0 {
+			g = e.cols
+		}
+		return e.cols*e.octs + (e.cols+g-1)/g
+	}
+	return e.hexWidth(e.cols)
+}
+
+// appendLine renders one hex, binary, octal or decimal dump line for the
+// octets in b, shown at offset off, e.g.
+// 0000010: 6973 2069 7320 7361 7475 7264 6179 2074   is is saturday t
+func (e *encoder) appendLine(dst []byte, off int64, b []byte) []byte {
 	var (
-		v   byte
-		n   int
-		err error
+		char [8]byte
+		cur  int
 	)
 
-	for {
-		n, err = io.ReadFull(r, line)
-		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			return err
-		}
-		// Speed it up a bit ;)
-		if dumpType == DumpPostscript && n != 0 {
-			// Post script values
-			// Basically just raw hex output
-			for i := 0; i < n; i++ {
-				hexEncode(char, line[i:i+1], caps)
-				w.Write(char)
-				c++
-			}
-			continue
-		}
+	dst = e.appendOffset(dst, off)
+	dst = append(dst, colonSpace...)
 
-		if n == 0 {
-			if dumpType == DumpPostscript {
-				w.Write(newLine)
-			}
+	if e.cfg.Compat && e.dumpType == DumpLittleEndian && e.cols%e.groupSize != 0 {
+		return e.appendVimLittleEndian(dst, b)
+	}
 
-			if dumpType == DumpCformat {
-				doCEnd = true
+	start := len(dst)
+	if e.dumpType == DumpLittleEndian {
+		dst = e.appendLittleEndian(dst, off, b)
+	} else if e.words > 0 {
+		dst = e.appendWordView(dst, b)
+	} else {
+		for i := 0; i < len(b); i++ {
+			dst = e.beginOctet(dst, b[i], off+int64(i), &cur)
+			if e.dumpType == DumpBinary {
+				binaryEncode(char[:8], b[i:i+1])
+			} else if e.dumpType != DumpHex {
+				numberEncode(char[:e.octs], b[i], e.dumpType)
 			} else {
-				return nil // Hidden return!
+				hexEncode(char[:2], b[i:i+1], e.caps)
 			}
-		}
+			dst = append(dst, char[:e.octs]...)
+			dst = e.endOctet(dst)
 
-		if xxdCfg.Length != -1 {
-			if totalOcts == xxdCfg.Length {
-				break
+			if e.groupSize > 0 && (i+1)%e.groupSize == 0 {
+				dst = append(dst, space...)
 			}
-			totalOcts += xxdCfg.Length
 		}
+		dst = e.endColumn(dst, &cur)
+	}
 
-		if xxdCfg.AutoSkip && empty(&line) {
-			if nulLine == 1 {
-				w.Write(asterisk)
-				w.Write(newLine)
-			}
-
-			nulLine++
-
-			if nulLine > 1 {
-				lineOffset++ // continue to increment our offset
-				continue
-			}
-		}
+	// Each line should have cols octets, pad out the deficit
+	for i := e.visibleLen(dst[start:]); i < e.lineWidth(); i++ {
+		dst = append(dst, space...)
+	}
+	if e.cfg.Compat {
+		dst = append(dst, space...)
+	} else {
+		dst = append(dst, twoSpaces...)
+	}
 
-		if xxdCfg.DumpType <= DumpBinary { // either hex or binary
-			// Line offset
-			hexOffset = strconv.AppendInt(hexOffset[0:0], lineOffset, 16)
-			w.Write(zeroHeader[0:(6 - len(hexOffset))])
-			w.Write(hexOffset)
-			w.Write(zeroHeader[6:])
-			lineOffset++
-		} else if doCHeader {
-			w.Write(varDeclChar)
-			w.Write(newLine)
-			doCHeader = false
-		}
+	// |hello, world!| instead of hello, world!
+	if e.cfg.Bars {
+		dst = append(dst, bar...)
+	}
+	dst = e.appendChars(dst, off, b)
+	if e.cfg.Bars {
+		dst = append(dst, bar...)
+	}
+	return append(dst, newLine...)
+}
 
-		if xxdCfg.DumpType == DumpBinary {
-			// Binary values
-			for i, k := 0, octs; i < n; i, k = i+1, k+octs {
-				binaryEncode(char, line[i:i+1])
-				w.Write(char)
-				c++
-
-				if k == octs*groupSize {
-					k = 0
-					w.Write(space)
-				}
-			}
-		} else if xxdCfg.DumpType == DumpCformat {
-			// C values
-			if !doCEnd {
-				w.Write(doubleSpace)
-			}
-			for i := 0; i < n; i++ {
-				cfmtEncode(char, line[i:i+1], caps)
-				w.Write(char)
-				c++
-
-				// don't add spaces to EOL
-				if i != n-1 {
-					w.Write(commaSpace)
-				} else if n == cols {
-					w.Write(comma)
-				}
-			}
+// appendChars renders the character column for the octets in b, shown
+// from offset off, in ASCII or EBCDIC, with a '.' for anything that does
+// not print
+func (e *encoder) appendChars(dst []byte, off int64, b []byte) []byte {
+	var cur int
+	for i, v := range b {
+		dst = e.beginOctet(dst, v, off+int64(i), &cur)
+		if c := e.char(v); e.html && (c == '<' || c == '>' || c == '&') {
+			dst = appendEntity(dst, c)
 		} else {
-			// Hex values -- default xxd FILE output
-			for i, k := 0, octs; i < n; i, k = i+1, k+octs {
-				hexEncode(char, line[i:i+1], caps)
-				w.Write(char)
-				c++
-
-				if k == octs*groupSize {
-					k = 0 // reset counter
-					w.Write(space)
-				}
-			}
+			dst = append(dst, c)
 		}
+		dst = e.endOctet(dst)
+	}
+	return e.endColumn(dst, &cur)
+}
 
-		if doCEnd {
-			w.Write(varDeclInt)
-			w.Write([]byte(strconv.FormatInt(c, 10)))
-			w.Write(semiColonNl)
-			return nil
+// char returns the character v shows as, in ASCII or EBCDIC
+func (e *encoder) char(v byte) byte {
+	if e.cfg.Ebcdic {
+		if v < ebcdicOffset {
+			return '.'
 		}
+		v = ebcdicTable[v-ebcdicOffset]
+	}
+	if v > 0x1f && v < 0x7f {
+		return v
+	}
+	return '.'
+}
 
-		if n < len(line) && dumpType <= DumpBinary {
-
-			//Each line should have len(line), but we have a deficit
-			maxGroupsPerLine := len(line) / groupSize
-
-			//'n' can fill up how many groups?
-			deficit := n
-			totalGroups := 1
-			for deficit > 0 {
-				deficit = deficit - groupSize
+// appendOffset renders a line offset, zero padded to OffsetWidth digits
+// (8 like vim's xxd and hexdump by default, 6 like od, or 7 for its
+// decimal offsets). Offsets that need more digits, e.g. past
+// 4 GiB, are never truncated, the column just widens.
+func (e *encoder) appendOffset(dst []byte, off int64) []byte {
+	var buf [24]byte
 
-				if deficit > 0 {
-					totalGroups++
-				}
-			}
-			if deficit < 0 {
-				//incomplete group
-				for ; deficit < 0; deficit++ {
-					w.Write(twoSpaces)
-				}
-				w.Write(space)
-			}
+	base := 16
+	if e.cfg.Decimal {
+		base = 10
+	}
+	width := e.cfg.OffsetWidth
+	switch {
+	case width != 0:
+	case e.layout == LayoutOd && e.cfg.Decimal:
+		width = 7
+	case e.layout == LayoutOd:
+		width = 6
+	default:
+		width = defaultOffsetWidth
+	}
 
-			//finish off the rest of the deficit groups
+	h := strconv.AppendInt(buf[:0], off, base)
+	start := len(dst)
+	for i := len(h); i < width; i++ {
+		dst = append(dst, '0')
+	}
+	dst = append(dst, h...)
+	if e.html {
+		// the offset names its own anchor
+		dst = appendAnchor(dst[:start], string(dst[start:]))
+	}
+	return dst
+}
 
-			for i := totalGroups; i < maxGroupsPerLine; i++ {
-				for i := 0; i < groupSize; i++ {
-					w.Write(twoSpaces)
-				}
-				w.Write(space)
-			}
+// appendLittleEndian renders the octets in b as little-endian words of
+// groupSize octets, i.e. byte swapped within each group. Like xxd -e a
+// short trailing group is right aligned, as if padded with leading zeros.
+func (e *encoder) appendLittleEndian(dst []byte, off int64, b []byte) []byte {
+	var (
+		char [2]byte
+		cur  int
+	)
+	for i := 0; i < len(b); i += e.groupSize {
+		grp := b[i:]
+		if len(grp) > e.groupSize {
+			grp = grp[:e.groupSize]
 		}
-
-		if dumpType != DumpCformat {
-			w.Write(space)
+		for k := len(grp); k < e.groupSize; k++ {
+			dst = append(dst, twoSpaces...)
 		}
+		for k := len(grp) - 1; k >= 0; k-- {
+			dst = e.beginOctet(dst, grp[k], off+int64(i+k), &cur)
+			hexEncode(char[:], grp[k:k+1], e.caps)
+			dst = append(dst, char[:]...)
+			dst = e.endOctet(dst)
+		}
+		if len(grp) == e.groupSize {
+			dst = append(dst, space...)
+		}
+	}
+	return e.endColumn(dst, &cur)
+}
 
-		if dumpType <= DumpBinary {
-
-			w.Write(space)
-
-			// Character values
-
-			b := line[:n]
+// appendVimLittleEndian renders the values and characters of a compat
+// -e line whose columns are not whole groups. vim's xxd places every
+// octet in a line buffer at the column of its byte swapped position,
+// which for the last, incomplete group lies past the group and runs into
+// the character column; this does the same.
+func (e *encoder) appendVimLittleEndian(dst []byte, b []byte) []byte {
+	g := e.groupSize
+	grpLen := g*e.octs + 1
+	chars := 2 + (grpLen*e.cols-1)/g // where the characters start
+
+	// the last group's values may lie past the characters
+	n := chars + len(b)
+	for p := range b {
+		if end := (grpLen*(p^(g-1)))/g + 2; end > n {
+			n = end
+		}
+	}
+	line := make([]byte, n)
+	for i := range line {
+		line[i] = ' '
+	}
+	for p, v := range b {
+		c := (grpLen * (p ^ (g - 1))) / g
+		line[c] = e.caps[v>>4]
+		line[c+1] = e.caps[v&0x0f]
+		line[chars+p] = e.char(v)
+	}
+	dst = append(dst, line[:chars+len(b)]...)
+	return append(dst, newLine...)
+}
 
-			// |hello, world!| instead of hello, world!
-			if xxdCfg.Bars {
-				w.Write(bar)
-			}
-			// EBCDIC
-			if xxdCfg.Ebcdic {
-				for i := 0; i < len(b); i++ {
-					v = b[i]
-					if v >= ebcdicOffset {
-						e := ebcdicTable[v-ebcdicOffset : v-ebcdicOffset+1]
-						if e[0] > 0x1f && e[0] < 0x7f {
-							w.Write(e)
-						} else {
-							w.Write(dot)
-						}
-					} else {
-						w.Write(dot)
-					}
-				}
-				if xxdCfg.Bars {
-					w.Write(bar)
-				}
-				// ASCII
-			} else {
-				var v byte
-				for i := 0; i < len(b); i++ {
-					v = b[i]
-					if v > 0x1f && v < 0x7f {
-						w.Write(line[i : i+1])
-					} else {
-						w.Write(dot)
-					}
-				}
-			}
+// appendPostscript renders b as plain hex, a line of its own unless the
+// whole dump is a single line (compat -c 0)
+func (e *encoder) appendPostscript(dst []byte, b []byte) []byte {
+	var char [2]byte
+	for i := 0; i < len(b); i++ {
+		hexEncode(char[:], b[i:i+1], e.caps)
+		dst = append(dst, char[:]...)
+	}
+	if e.unbroken {
+		return dst
+	}
+	return append(dst, newLine...)
+}
 
-			if xxdCfg.Bars {
-				w.Write(bar)
-			}
+// appendCformat renders b as one line of a C array initializer. Every
+// line but the last carries a trailing comma.
+func (e *encoder) appendCformat(dst []byte, b []byte, last bool) []byte {
+	var char [4]byte
+	dst = append(dst, doubleSpace...)
+	for i := 0; i < len(b); i++ {
+		cfmtEncode(char[:], b[i:i+1], e.caps)
+		if e.cfg.Compat && e.cfg.Upper {
+			char[1] = 'X'
+		}
+		dst = append(dst, char[:]...)
 
+		// don't add spaces to EOL
+		if i != len(b)-1 {
+			dst = append(dst, commaSpace...)
+		} else if !last {
+			dst = append(dst, comma...)
 		}
-		w.Write(newLine)
-		nl++
 	}
-	return nil
+	return append(dst, newLine...)
 }
 
 // convert a byte into its binary representation
@@ -368,6 +516,57 @@ func binaryDecode(dst, src []byte) int {
 	return -1
 }
 
+// numberEncode renders v in the fixed width digits of an octal, decimal
+// or signed decimal dump: 377, 255 or -001 for 0xff
+func numberEncode(dst []byte, v byte, dumpType int) {
+	n, base := int(v), 10
+	switch dumpType {
+	case DumpOctal:
+		base = 8
+	case DumpSigned:
+		n = int(int8(v))
+		dst[0] = '+'
+		if n < 0 {
+			dst[0], n = '-', -n
+		}
+		dst = dst[1:]
+	}
+	for i := len(dst) - 1; i >= 0; i-- {
+		dst[i] = byte('0' + n%base)
+		n /= base
+	}
+}
+
+// numberDecode parses the digits numberEncode renders for dumpType, ok
+// is false when src is not one of its values
+func numberDecode(src []byte, dumpType int) (v byte, ok bool) {
+	base, neg := 10, false
+	switch dumpType {
+	case DumpOctal:
+		base = 8
+	case DumpSigned:
+		if src[0] != '+' && src[0] != '-' {
+			return 0, false
+		}
+		neg, src = src[0] == '-', src[1:]
+	}
+
+	n := 0
+	for _, c := range src {
+		if c < '0' || int(c-'0') >= base {
+			return 0, false
+		}
+		n = n*base + int(c-'0')
+	}
+	switch {
+	case neg && n <= 128:
+		return byte(-n), true
+	case !neg && n <= 255 && (dumpType != DumpSigned || n <= 127):
+		return byte(n), true
+	}
+	return 0, false
+}
+
 func cfmtEncode(dst, src []byte, hextable string) {
 	b := src[0]
 	dst[3] = hextable[b&0x0f]
@@ -383,39 +582,6 @@ func hexEncode(dst, src []byte, hextable string) {
 	dst[0] = hextable[b>>4]
 }
 
-// copied from encoding/hex package
-// returns -1 on bad byte or space (\t \s \n)
-// returns -2 on two consecutive spaces
-// returns 0 on success
-func hexDecode(dst, src []byte) int {
-	_, _ = src[2], dst[0]
-
-	if isSpace(src[0]) {
-		if isSpace(src[1]) {
-			return -2
-		}
-		return -1
-	}
-
-	if isPrefix(src[0:2]) {
-		src = src[2:]
-	}
-
-	for i := 0; i < len(src)/2; i++ {
-		a, ok := fromHexChar(src[i*2])
-		if !ok {
-			return -1
-		}
-		b, ok := fromHexChar(src[i*2+1])
-		if !ok {
-			return -1
-		}
-
-		dst[0] = (a << 4) | b
-	}
-	return 0
-}
-
 // copied from encoding/hex package
 func fromHexChar(c byte) (byte, bool) {
 	switch {
@@ -431,106 +597,15 @@ func fromHexChar(c byte) (byte, bool) {
 }
 
 // check if entire line is full of empty []byte{0} bytes (nul in C)
-func empty(b *[]byte) bool {
-	for i := 0; i < len(*b); i++ {
-		if (*b)[i] != 0 {
+func empty(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		if b[i] != 0 {
 			return false
 		}
 	}
 	return true
 }
 
-// quick binary tree check
-// probably horribly written idk it's late at night
-func parseSpecifier(b string) float64 {
-	lb := len(b)
-	if lb == 0 {
-		return 0
-	}
-
-	var b0, b1 byte
-	if lb < 2 {
-		b0 = b[0]
-		b1 = '0'
-	} else {
-		b1 = b[1]
-		b0 = b[0]
-	}
-
-	if b1 != '0' {
-		if b1 == 'b' { // bits, so convert bytes to bits for os.Seek()
-			if b0 == 'k' || b0 == 'K' {
-				return 0.0078125
-			}
-
-			if b0 == 'm' || b0 == 'M' {
-				return 7.62939453125e-06
-			}
-
-			if b0 == 'g' || b0 == 'G' {
-				return 7.45058059692383e-09
-			}
-		}
-
-		if b1 == 'B' { // kilo/mega/giga- bytes are assumed
-			if b0 == 'k' || b0 == 'K' {
-				return 1024
-			}
-
-			if b0 == 'm' || b0 == 'M' {
-				return 1048576
-			}
-
-			if b0 == 'g' || b0 == 'G' {
-				return 1073741824
-			}
-		}
-	} else { // kilo/mega/giga- bytes are assumed for single b, k, m, g
-		if b0 == 'k' || b0 == 'K' {
-			return 1024
-		}
-
-		if b0 == 'm' || b0 == 'M' {
-			return 1048576
-		}
-
-		if b0 == 'g' || b0 == 'G' {
-			return 1073741824
-		}
-	}
-
-	return 1 // assumes bytes as fallback
-}
-
-// parses *seek input
-func parseSeek(s string) int64 {
-	var (
-		sl    = len(s)
-		split int
-	)
-
-	switch {
-	case sl >= 2:
-		if sl == 2 {
-			split = 1
-		} else {
-			split = 2
-		}
-	case sl != 0:
-		split = 0
-	default:
-		log.Fatalln("seek string somehow has len of 0")
-	}
-
-	mod := parseSpecifier(s[sl-split:])
-	ret, err := strconv.ParseFloat(s[:sl-split], 64) // 64 bit float
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	return int64(ret * mod)
-}
-
 // is byte a space? (\t, \n, \s)
 func isSpace(b byte) bool {
 	switch b {
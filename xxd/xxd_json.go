@@ -0,0 +1,307 @@
+package xxd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// isJSON reports whether dumpType writes JSON objects
+func isJSON(dumpType int) bool {
+	return dumpType == DumpJSON || dumpType == DumpNDJSON
+}
+
+// appendJSONLine renders the octets in b, shown at offset off, as a
+// single object, e.g.
+// {"offset":16,"hex":"6973206973","chars":"is is","count":5}
+func (e *encoder) appendJSONLine(dst []byte, off int64, b []byte) []byte {
+	var char [2]byte
+
+	dst = append(dst, `{"offset":`...)
+	dst = strconv.AppendInt(dst, off, 10)
+	dst = append(dst, `,"hex":"`...)
+	for i := range b {
+		hexEncode(char[:], b[i:i+1], e.caps)
+		dst = append(dst, char[:]...)
+	}
+	dst = append(dst, `","chars":"`...)
+
+	// only printable ASCII makes it into the character column
+	start := len(dst)
+	dst = e.appendChars(dst, off, b)
+	chars := string(dst[start:])
+	dst = dst[:start]
+	for i := 0; i < len(chars); i++ {
+		if chars[i] == '"' || chars[i] == '\\' {
+			dst = append(dst, '\\')
+		}
+		dst = append(dst, chars[i])
+	}
+
+	dst = append(dst, `","count":`...)
+	dst = strconv.AppendInt(dst, int64(len(b)), 10)
+	return append(dst, '}')
+}
+
+// appendJSONSkip renders a run of nul lines collapsed by autoskip, the n
+// octets from offset off, e.g.
+// {"offset":32,"end":96,"count":64,"skip":true}
+func (e *encoder) appendJSONSkip(dst []byte, off, n int64) []byte {
+	dst = append(dst, `{"offset":`...)
+	dst = strconv.AppendInt(dst, off, 10)
+	dst = append(dst, `,"end":`...)
+	dst = strconv.AppendInt(dst, off+n, 10)
+	dst = append(dst, `,"count":`...)
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, `,"skip":true}`...)
+}
+
+// writeJSONLine writes the object for the octets in b, shown at offset
+// off. With Config.AutoSkip, complete nul lines are held back until the
+// run they start ends, see flushSkip.
+func (d *Dumper) writeJSONLine(off int64, b []byte) error {
+	if d.e.cfg.AutoSkip && len(b) == d.e.cols && empty(b) {
+		if d.skipped == 0 {
+			d.skipOff = off
+		}
+		d.skipped += int64(len(b))
+		return nil
+	}
+	if err := d.flushSkip(); err != nil {
+		return err
+	}
+	d.line = d.e.appendJSONLine(d.line[:0], off, b)
+	return d.writeJSON(d.line)
+}
+
+// flushSkip writes the run of nul lines held back: a single line as it
+// is, longer runs as one skip object
+func (d *Dumper) flushSkip() error {
+	n := d.skipped
+	if n == 0 {
+		return nil
+	}
+	d.skipped = 0
+	if n == int64(d.e.cols) {
+		d.line = d.e.appendJSONLine(d.line[:0], d.skipOff, make([]byte, n))
+	} else {
+		d.line = d.e.appendJSONSkip(d.line[:0], d.skipOff, n)
+	}
+	return d.writeJSON(d.line)
+}
+
+// writeJSON writes one object, a line of its own in NDJSON and an element
+// of the array, which the first one opens, in JSON
+func (d *Dumper) writeJSON(obj []byte) error {
+	if d.e.dumpType == DumpJSON {
+		sep := []byte(",\n")
+		if !d.header {
+			d.header = true
+			sep = []byte("[\n")
+		}
+		if err := d.write(sep); err != nil {
+			return err
+		}
+		return d.write(obj)
+	}
+	if err := d.write(obj); err != nil {
+		return err
+	}
+	return d.write(newLine)
+}
+
+// writeJSONEnd writes the run of nul lines still held back and closes
+// the array of a JSON document
+func (d *Dumper) writeJSONEnd() error {
+	if err := d.flushSkip(); err != nil {
+		return err
+	}
+	switch {
+	case d.e.dumpType != DumpJSON:
+		return nil
+	case !d.header:
+		return d.write([]byte("[]\n"))
+	}
+	return d.write([]byte("\n]\n"))
+}
+
+// jsonObject is a line of a JSON dump read back. Fields left out are nil.
+type jsonObject struct {
+	Offset *int64  `json:"offset"`
+	Hex    *string `json:"hex"`
+	Count  *int64  `json:"count"`
+	End    *int64  `json:"end"`
+	Skip   bool    `json:"skip"`
+}
+
+// jsonParser reads the objects of a JSON or NDJSON dump back, whatever
+// the white space between them
+type jsonParser struct {
+	r       *bufio.Reader
+	lines   *lineCounter
+	dec     *json.Decoder
+	skipped int64 // white space read before dec took over
+	array   bool  // the objects are in an array
+	last    int   // line the last object decoded ends on
+}
+
+func newJSONParser(r io.Reader) (*jsonParser, *bufio.Reader) {
+	p := &jsonParser{lines: &lineCounter{r: r}}
+	p.r = bufio.NewReader(p.lines)
+	return p, p.r
+}
+
+// start tells a JSON document from NDJSON by its first character
+func (p *jsonParser) start() error {
+	for {
+		c, err := p.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !isSpace(c) && c != '\r' && c != '\n' {
+			p.r.UnreadByte()
+			p.array = c == '['
+			break
+		}
+		p.skipped++
+	}
+
+	p.dec = json.NewDecoder(p.r)
+	if p.array {
+		p.dec.Token() // the '['
+	}
+	return nil
+}
+
+// decode appends the octets of the next object to dst and returns the
+// offset they belong at; ok is false for objects without one. A skip
+// object carries no octets, its end is returned for the caller to fill
+// up to like an xxd autoskip.
+func (p *jsonParser) decode(dst []byte) (off int64, out []byte, ok bool, err error) {
+	if p.dec == nil {
+		if err := p.start(); err != nil {
+			return 0, dst, false, err
+		}
+	}
+
+	if p.array && !p.dec.More() {
+		// the ']', or a truncated document
+		_, err := p.dec.Token()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, dst, false, p.error(err)
+		}
+		if _, err := p.dec.Token(); err != io.EOF {
+			at := p.dec.InputOffset()
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				at = syntax.Offset
+			}
+			return 0, dst, false, &DecodeError{p.line(at), "data after the array"}
+		}
+		return 0, dst, false, io.EOF
+	}
+
+	var obj jsonObject
+	if err := p.dec.Decode(&obj); err != nil {
+		if err == io.EOF && p.array {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, dst, false, p.error(err)
+	}
+	line := p.line(p.dec.InputOffset())
+	p.last = line
+
+	if obj.Offset != nil {
+		off, ok = *obj.Offset, true
+	}
+	if obj.Skip {
+		switch {
+		case !ok:
+			return 0, dst, false, &DecodeError{line, "skip object without an offset"}
+		case obj.End != nil:
+			return *obj.End, dst, true, nil
+		case obj.Count != nil:
+			return off + *obj.Count, dst, true, nil
+		}
+		return 0, dst, false, &DecodeError{line, "skip object without an end or count"}
+	}
+
+	start := len(dst)
+	if obj.Hex != nil {
+		var good bool
+		if dst, good = decodeHexPairs(dst, []byte(*obj.Hex)); !good {
+			return 0, dst[:start], false, &DecodeError{line, "hex is not pairs of hex digits"}
+		}
+	}
+	if n := int64(len(dst) - start); obj.Count != nil && *obj.Count != n {
+		return 0, dst[:start], false, &DecodeError{line, fmt.Sprintf("count is %d, hex holds %d octets", *obj.Count, n)}
+	}
+	return off, dst, ok, nil
+}
+
+// error turns what the JSON decoder returned into a *DecodeError, except
+// for the end of the input
+func (p *jsonParser) error(err error) error {
+	var syntax *json.SyntaxError
+	switch {
+	case err == io.EOF:
+		return err
+	case errors.As(err, &syntax):
+		return &DecodeError{p.line(syntax.Offset), "invalid JSON: " + err.Error()}
+	case err == io.ErrUnexpectedEOF:
+		return &DecodeError{p.line(p.dec.InputOffset()), "unexpected end of JSON"}
+	}
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &typ) {
+		return &DecodeError{p.line(p.dec.InputOffset()), "invalid JSON: " + err.Error()}
+	}
+	return err
+}
+
+// line returns the number of the line at the offset off of the JSON
+// decoder's input
+func (p *jsonParser) line(off int64) int {
+	return p.lines.line(p.skipped + off)
+}
+
+// lineCounter is an io.Reader noting where the lines of what it reads
+// start, for reporting errors. Only the line ends past the last position
+// asked about are kept.
+type lineCounter struct {
+	r    io.Reader
+	pos  int64   // octets read so far
+	ends []int64 // positions of '\n' not yet passed
+	n    int     // '\n's passed
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for i, v := range p[:n] {
+		if v == '\n' {
+			c.ends = append(c.ends, c.pos+int64(i))
+		}
+	}
+	c.pos += int64(n)
+	return n, err
+}
+
+// line returns the 1-based number of the line holding position off,
+// which must not be before one asked about earlier
+func (c *lineCounter) line(off int64) int {
+	i := 0
+	for i < len(c.ends) && c.ends[i] < off {
+		i++
+	}
+	c.n += i
+	c.ends = c.ends[i:]
+	return c.n + 1
+}
@@ -0,0 +1,189 @@
+package xxd
+
+import (
+	"bytes"
+)
+
+// Names of the Config.Layout presets. Hex dumps are laid out like vim's
+// xxd unless one of the others is picked.
+const (
+	LayoutXxd     = "xxd"
+	LayoutHexdump = "hexdump-canonical" // hexdump -C
+	LayoutOd      = "od"                // od -A x -t x1z
+)
+
+// hexdump -C and od both close their dumps with the offset the input ends
+// at and replace repeated lines with a '*'
+
+// appendHexdumpLine renders one line the way hexdump -C does, e.g.
+// 00000010  69 73 20 69 73 20 73 61  74 75 72 64 61 79 20 74  |is is saturday t|
+// Groups, 8 octets unless Config.Group says otherwise, are split by an
+// extra space.
+func (e *encoder) appendHexdumpLine(dst []byte, off int64, b []byte) []byte {
+	var (
+		char [2]byte
+		cur  int
+	)
+
+	dst = e.appendOffset(dst, off)
+	dst = append(dst, twoSpaces...)
+
+	start := len(dst)
+	for i := 0; i < len(b); i++ {
+		if i > 0 {
+			dst = append(dst, space...)
+			if e.groupSize > 0 && i%e.groupSize == 0 {
+				dst = append(dst, space...)
+			}
+		}
+		dst = e.beginOctet(dst, b[i], off+int64(i), &cur)
+		hexEncode(char[:], b[i:i+1], e.caps)
+		dst = append(dst, char[:]...)
+		dst = e.endOctet(dst)
+	}
+	dst = e.endColumn(dst, &cur)
+
+	width := e.cols*3 - 1
+	if e.groupSize > 0 {
+		width += (e.cols - 1) / e.groupSize
+	}
+	for i := e.visibleLen(dst[start:]); i < width; i++ {
+		dst = append(dst, space...)
+	}
+
+	dst = append(dst, "  |"...)
+	dst = e.appendChars(dst, off, b)
+	return append(dst, "|\n"...)
+}
+
+// appendOdLine renders one line the way od -A x -t x1z does, e.g.
+// 000010 69 73 20 69 73 20 73 61 74 75 72 64 61 79 20 74  >is is saturday t<
+func (e *encoder) appendOdLine(dst []byte, off int64, b []byte) []byte {
+	var (
+		char [2]byte
+		cur  int
+	)
+
+	dst = e.appendOffset(dst, off)
+	for i := 0; i < len(b); i++ {
+		dst = append(dst, space...)
+		dst = e.beginOctet(dst, b[i], off+int64(i), &cur)
+		hexEncode(char[:], b[i:i+1], e.caps)
+		dst = append(dst, char[:]...)
+		dst = e.endOctet(dst)
+	}
+	dst = e.endColumn(dst, &cur)
+	for i := len(b); i < e.cols; i++ {
+		dst = append(dst, "   "...)
+	}
+
+	if e.html {
+		dst = append(dst, "  &gt;"...)
+		dst = e.appendChars(dst, off, b)
+		return append(dst, "&lt;\n"...)
+	}
+	dst = append(dst, "  >"...)
+	dst = e.appendChars(dst, off, b)
+	return append(dst, "<\n"...)
+}
+
+// appendLayoutEnd renders the line holding just the offset the input ends
+// at. hexdump leaves it out of the dump of nothing at all, od does not.
+func (e *encoder) appendLayoutEnd(dst []byte, off int64) []byte {
+	if e.layout == LayoutHexdump && off == 0 {
+		return dst
+	}
+	dst = e.appendOffset(dst, off)
+	return append(dst, newLine...)
+}
+
+// squeezeLine writes the line rendered for b unless it repeats the
+// octets of the line before it. Like hexdump and od without -v, a run of
+// repeats shows as a single '*'.
+func (d *Dumper) squeezeLine(b []byte) error {
+	if !d.e.cfg.Verbose && len(d.prev) > 0 && bytes.Equal(b, d.prev) {
+		if d.squeezed {
+			return nil
+		}
+		d.squeezed = true
+		return d.write([]byte("*\n"))
+	}
+	d.squeezed = false
+	d.prev = append(d.prev[:0], b...)
+	return d.write(d.line)
+}
+
+// layoutParser keeps the state of a hexdump -C or od dump being reversed
+type layoutParser struct {
+	delim  byte   // opens the character column, '|' or '>'
+	prev   []byte // octets of the last line
+	next   int64  // offset following the last line
+	repeat bool   // the last line was a '*'
+}
+
+func newLayoutParser(layout string) *layoutParser {
+	p := &layoutParser{delim: '|'}
+	if layout == LayoutOd {
+		p.delim = '>'
+	}
+	return p
+}
+
+// decode appends the octets of line, the n-th line of the dump, to dst
+// and returns the offset in front of them. After a '*' the line before is
+// repeated up to that offset; runs of nul lines are left for the caller
+// to fill like an xxd autoskip. At most cols octets are taken when cols is
+// positive.
+func (p *layoutParser) decode(dst, line []byte, n, cols int, decimal bool) (off int64, out []byte, ok bool, err error) {
+	line = trimSpace(line)
+	switch {
+	case len(line) == 0:
+		return 0, dst, false, nil
+	case len(line) == 1 && line[0] == '*':
+		p.repeat = true
+		return 0, dst, false, nil
+	}
+
+	i := 0
+	for i < len(line) && !isSpace(line[i]) {
+		i++
+	}
+	if off, ok = parseOffset(line[:i], decimal); !ok {
+		return 0, dst, false, &DecodeError{n, "line does not start with an offset"}
+	}
+
+	start := len(dst)
+	for line = line[i:]; len(line) > 0; {
+		if isSpace(line[0]) {
+			line = line[1:]
+			continue
+		}
+		if line[0] == p.delim || cols > 0 && len(dst)-start == cols || len(line) < 2 {
+			break
+		}
+		a, ok1 := fromHexChar(line[0])
+		b, ok2 := fromHexChar(line[1])
+		if !ok1 || !ok2 || len(line) > 2 && !isSpace(line[2]) {
+			return 0, dst, false, &DecodeError{n, "not a hex octet"}
+		}
+		dst = append(dst, a<<4|b)
+		line = line[2:]
+	}
+	b := dst[start:]
+
+	if p.repeat && off > p.next && !empty(p.prev) {
+		b = append([]byte(nil), b...)
+		dst = dst[:start]
+		for k := int64(0); k < off-p.next; k++ {
+			dst = append(dst, p.prev[k%int64(len(p.prev))])
+		}
+		dst = append(dst, b...)
+		off = p.next
+	}
+	p.repeat = false
+	if len(b) > 0 {
+		p.prev = append(p.prev[:0], b...)
+	}
+	p.next = off + int64(len(dst)-start)
+	return off, dst, true, nil
+}
@@ -0,0 +1,137 @@
+package xxd
+
+import (
+	"strconv"
+)
+
+// isMemInit reports whether dumpType writes an FPGA memory initialisation
+// file, made of words of Group octets
+func isMemInit(dumpType int) bool {
+	switch dumpType {
+	case DumpReadmemh, DumpCoe, DumpMif:
+		return true
+	}
+	return false
+}
+
+// radixNames are the MIF names for the address radixes
+var radixNames = map[int]string{2: "BIN", 8: "OCT", 10: "DEC", 16: "HEX"}
+
+// appendWord renders the first groupSize octets of b as one hex word, in
+// Config.LittleEndian order. A short last word is padded with zero octets,
+// as the memory it initialises has no narrower cells.
+func (e *encoder) appendWord(dst, b []byte) []byte {
+	for i := 0; i < e.groupSize; i++ {
+		k := i
+		if e.cfg.LittleEndian {
+			k = e.groupSize - 1 - i
+		}
+		var v byte
+		if k < len(b) {
+			v = b[k]
+		}
+		dst = append(dst, e.caps[v>>4], e.caps[v&0x0f])
+	}
+	return dst
+}
+
+// appendWords renders the words in b separated by sep
+func (e *encoder) appendWords(dst, b []byte, sep string) []byte {
+	for i := 0; i < len(b); i += e.groupSize {
+		if i > 0 {
+			dst = append(dst, sep...)
+		}
+		dst = e.appendWord(dst, b[i:])
+	}
+	return dst
+}
+
+// appendReadmemh renders b as a line of Verilog $readmemh words. The
+// first line of a memory that does not start at 0 is preceded by an @
+// word address.
+func (e *encoder) appendReadmemh(dst []byte, off int64, b []byte, first bool) []byte {
+	if first && off != 0 {
+		dst = append(dst, '@')
+		dst = strconv.AppendInt(dst, off/int64(e.groupSize), 16)
+		dst = append(dst, newLine...)
+	}
+	dst = e.appendWords(dst, b, " ")
+	return append(dst, newLine...)
+}
+
+// appendCoeHeader renders the radix and vector keywords of a Xilinx .coe
+// file
+func (e *encoder) appendCoeHeader(dst []byte) []byte {
+	return append(dst, "memory_initialization_radix=16;\nmemory_initialization_vector=\n"...)
+}
+
+// appendCoe renders b as a line of a .coe initialization vector, the last
+// one ending it with ';'
+func (e *encoder) appendCoe(dst []byte, b []byte, last bool) []byte {
+	dst = e.appendWords(dst, b, ", ")
+	if last {
+		dst = append(dst, ';')
+	} else {
+		dst = append(dst, ',')
+	}
+	return append(dst, newLine...)
+}
+
+// appendMifHeader renders the header of an Intel .mif file for a memory
+// ending just before octet end
+func (e *encoder) appendMifHeader(dst []byte, fname string, end int64) []byte {
+	if fname != "-" && fname != "" {
+		dst = append(dst, "-- "...)
+		dst = append(dst, fname...)
+		dst = append(dst, newLine...)
+	}
+	dst = append(dst, "WIDTH="...)
+	dst = strconv.AppendInt(dst, int64(e.groupSize*8), 10)
+	dst = append(dst, ";\nDEPTH="...)
+	dst = strconv.AppendInt(dst, e.mifDepth(end), 10)
+	dst = append(dst, ";\n\nADDRESS_RADIX="...)
+	dst = append(dst, radixNames[e.addressRadix()]...)
+	return append(dst, ";\nDATA_RADIX=HEX;\n\nCONTENT BEGIN\n"...)
+}
+
+// appendMif renders b as one line of .mif content, the words at
+// consecutive addresses from that of offset off. Addresses are padded to
+// the digits of the last one.
+func (e *encoder) appendMif(dst []byte, off int64, b []byte, end int64) []byte {
+	var buf [64]byte
+
+	radix := e.addressRadix()
+	last := strconv.AppendInt(buf[:0], e.mifDepth(end)-1, radix)
+	addr := strconv.AppendInt(buf[len(last):len(last)], off/int64(e.groupSize), radix)
+
+	dst = append(dst, '\t')
+	for i := len(addr); i < len(last); i++ {
+		dst = append(dst, '0')
+	}
+	dst = append(dst, addr...)
+	dst = append(dst, " : "...)
+	dst = e.appendWords(dst, b, " ")
+	return append(dst, ";\n"...)
+}
+
+// appendMifEnd closes the content of a .mif file
+func (e *encoder) appendMifEnd(dst []byte) []byte {
+	return append(dst, "END;\n"...)
+}
+
+// mifDepth is the number of words in a memory ending just before octet
+// end, at least one
+func (e *encoder) mifDepth(end int64) int64 {
+	g := int64(e.groupSize)
+	if depth := (end + g - 1) / g; depth > 0 {
+		return depth
+	}
+	return 1
+}
+
+func (e *encoder) addressRadix() int {
+	if e.cfg.AddressRadix == 0 {
+		return 16
+	}
+	return e.cfg.AddressRadix
+}
@@ -0,0 +1,248 @@
+package xxd
+
+import (
+	"io"
+)
+
+// Option sets a single Config field, see NewConfig
+type Option func(cfg *Config)
+
+// NewConfig returns a Config with the same defaults as the xxd command
+// line (a hex dump with the format's default columns and grouping and no
+// length limit) and applies opts to it in order.
+func NewConfig(opts ...Option) *Config {
+	cfg := &Config{
+		DumpType: DumpHex,
+		Columns:  -1,
+		Group:    -1,
+		Length:   -1,
+
+		StartAddress: -1,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// XxdWith is Xxd with a Config built by NewConfig(opts...)
+func XxdWith(r io.Reader, w io.Writer, fname string, opts ...Option) error {
+	return Xxd(r, w, fname, NewConfig(opts...))
+}
+
+// XxdReverseWith is XxdReverse with a Config built by NewConfig(opts...)
+func XxdReverseWith(r io.Reader, w io.Writer, opts ...Option) error {
+	return XxdReverse(r, w, NewConfig(opts...))
+}
+
+// WithFormat selects the dump type, one of the Dump* constants
+func WithFormat(dumpType int) Option {
+	return func(cfg *Config) {
+		cfg.DumpType = dumpType
+	}
+}
+
+// WithColumns sets the number of octets per line (-c)
+func WithColumns(cols int) Option {
+	return func(cfg *Config) {
+		cfg.Columns = cols
+	}
+}
+
+// WithGroup sets the number of octets per group (-g)
+func WithGroup(group int) Option {
+	return func(cfg *Config) {
+		cfg.Group = group
+	}
+}
+
+// WithLength stops after length octets (-l)
+func WithLength(length int64) Option {
+	return func(cfg *Config) {
+		cfg.Length = length
+	}
+}
+
+// WithSeek sets the offset to start at (-s), e.g. "1k"
+func WithSeek(seek string) Option {
+	return func(cfg *Config) {
+		cfg.Seek = seek
+	}
+}
+
+// WithDisplayOffset adds off to the offsets shown (-o)
+func WithDisplayOffset(off int64) Option {
+	return func(cfg *Config) {
+		cfg.DisplayOffset = off
+	}
+}
+
+// WithSeekOffsets shows offsets from the start of the input rather than
+// from the Seek position
+func WithSeekOffsets(cfg *Config) {
+	cfg.SeekOffsets = true
+}
+
+// WithOffsetWidth zero pads offsets to at least width digits
+func WithOffsetWidth(width int) Option {
+	return func(cfg *Config) {
+		cfg.OffsetWidth = width
+	}
+}
+
+// WithDecimal shows offsets in decimal (-d)
+func WithDecimal(cfg *Config) {
+	cfg.Decimal = true
+}
+
+// WithCompat makes the output byte-for-byte that of vim's xxd: a single
+// space before the character column, short trailing groups padded as if
+// complete, offsets that include the Seek position, 0X literals for -i -u
+// and vim's C variable names. -c 0 is taken as the default columns, or a
+// single line for -ps, and -e lines that end in part of a group overlap
+// their character column just like vim's, which loses octets: such dumps
+// cannot be read back. testdata/compat holds the corpus this is checked
+// against.
+func WithCompat(cfg *Config) {
+	cfg.Compat = true
+}
+
+// WithLayout lays hex dumps out like another tool, LayoutHexdump for
+// hexdump -C or LayoutOd for od -A x -t x1z. The output is byte-for-byte
+// theirs, including the closing offset and the '*' replacing repeated
+// lines; AutoSkip and Bars do not apply.
+func WithLayout(layout string) Option {
+	return func(cfg *Config) {
+		cfg.Layout = layout
+	}
+}
+
+// WithVerbose prints every line of a hexdump or od layout, repeated ones
+// included, like their -v
+func WithVerbose(cfg *Config) {
+	cfg.Verbose = true
+}
+
+// WithColor colours the octets of dumps with a character column by class,
+// in both columns, like xxd -R always. The words of a word view are
+// coloured when their octets share a class and left plain otherwise.
+func WithColor(cfg *Config) {
+	cfg.Color = true
+}
+
+// WithPalette colours with p instead of DefaultPalette, see ParsePalette
+func WithPalette(p Palette) Option {
+	return func(cfg *Config) {
+		cfg.Palette = p
+	}
+}
+
+// WithHTML writes the dump as a self-contained HTML page: offsets are
+// anchors linking to themselves, octets are coloured by class, and
+// pointing at one highlights it in both columns. Any dump with a
+// character column can be, but XxdReverse does not read pages back.
+func WithHTML(cfg *Config) {
+	cfg.HTML = true
+}
+
+// WithVarName sets the C include variable name (-n)
+func WithVarName(name string) Option {
+	return func(cfg *Config) {
+		cfg.VarName = name
+	}
+}
+
+// WithCapitalize upper cases C include variable names (-C)
+func WithCapitalize(cfg *Config) {
+	cfg.Capitalize = true
+}
+
+// WithStatic declares C include arrays static
+func WithStatic(cfg *Config) {
+	cfg.Static = true
+}
+
+// WithConst declares C include arrays const
+func WithConst(cfg *Config) {
+	cfg.Const = true
+}
+
+// WithAlign declares C include arrays alignas(align)
+func WithAlign(align int) Option {
+	return func(cfg *Config) {
+		cfg.Align = align
+	}
+}
+
+// WithPackage sets the package clause of DumpGo output
+func WithPackage(name string) Option {
+	return func(cfg *Config) {
+		cfg.Package = name
+	}
+}
+
+// WithGoString makes DumpGo declare a const string of \x escapes instead
+// of a []byte, which compiles faster for large blobs
+func WithGoString(cfg *Config) {
+	cfg.GoString = true
+}
+
+// WithStartAddress writes addr as the entry point of Intel HEX and
+// S-record output
+func WithStartAddress(addr int64) Option {
+	return func(cfg *Config) {
+		cfg.StartAddress = addr
+	}
+}
+
+// WithAddressWidth fixes the S-record address width to 2, 3 or 4 bytes,
+// i.e. S19, S28 or S37 files. Without it the width fits the image when
+// its size is known up front, and is 3 bytes for streams, in either case
+// widened to fit a WithStartAddress.
+func WithAddressWidth(width int) Option {
+	return func(cfg *Config) {
+		cfg.AddressWidth = width
+	}
+}
+
+// WithLittleEndian stores the words of memory initialisation files and
+// word views least significant octet first
+func WithLittleEndian(cfg *Config) {
+	cfg.LittleEndian = true
+}
+
+// WithWordSize shows hex, octal, decimal and float dumps as words of size
+// octets, 2, 4 or 8, rather than octet by octet, e.g. WithFormat(DumpDecimal)
+// and WithWordSize(4) for od -t u4
+func WithWordSize(size int) Option {
+	return func(cfg *Config) {
+		cfg.WordSize = size
+	}
+}
+
+// WithAddressRadix sets the base, 2, 8, 10 or 16, of MIF addresses
+func WithAddressRadix(radix int) Option {
+	return func(cfg *Config) {
+		cfg.AddressRadix = radix
+	}
+}
+
+// WithUpper uses upper case hex letters (-u)
+func WithUpper(cfg *Config) {
+	cfg.Upper = true
+}
+
+// WithAutoSkip replaces runs of nul lines with a single '*' (-a)
+func WithAutoSkip(cfg *Config) {
+	cfg.AutoSkip = true
+}
+
+// WithBars prints |pipes| around the character column (-B)
+func WithBars(cfg *Config) {
+	cfg.Bars = true
+}
+
+// WithEbcdic shows the character column in EBCDIC (-E)
+func WithEbcdic(cfg *Config) {
+	cfg.Ebcdic = true
+}
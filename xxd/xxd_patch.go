@@ -0,0 +1,65 @@
+package xxd
+
+import (
+	"io"
+)
+
+// zero block written over gaps when Config.FillGaps is set
+var zeros = make([]byte, 4096)
+
+// XxdPatch converts the dump read from r back into binary like XxdReverse,
+// but writes the octets of each line at the offset in front of it, the
+// way xxd -r patches an existing file. Bytes of w that no line covers are
+// left untouched unless xxdCfg.FillGaps is set, in which case the gaps
+// between lines are overwritten with zeros.
+//
+// Intel HEX and S-records are written at their addresses, in whatever order
+// they come. Postscript and C include dumps carry no offsets, their
+// octets are written one after another from the start of w.
+func XxdPatch(r io.Reader, w io.WriterAt, xxdCfg *Config) error {
+	var (
+		d    = NewDecoder(r, xxdCfg)
+		pos  int64 // where the next line without an offset goes
+		left = xxdCfg.Length
+	)
+
+	for left != 0 {
+		off, ok, b, err := d.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			off = pos
+		}
+		if left > 0 {
+			if int64(len(b)) > left {
+				b = b[:left]
+			}
+			left -= int64(len(b))
+		}
+
+		if xxdCfg.FillGaps {
+			for pos < off {
+				n := int64(len(zeros))
+				if off-pos < n {
+					n = off - pos
+				}
+				if _, err := w.WriteAt(zeros[:n], pos); err != nil {
+					return err
+				}
+				pos += n
+			}
+		}
+
+		if len(b) > 0 {
+			if _, err := w.WriteAt(b, off); err != nil {
+				return err
+			}
+		}
+		pos = off + int64(len(b))
+	}
+	return nil
+}
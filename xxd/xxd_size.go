@@ -0,0 +1,85 @@
+package xxd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the postfixes accepted by ParseSize to the number of
+// bytes they stand for. Units are multiples of 1024, lower case b means
+// bits.
+var sizeUnits = map[string]float64{
+	"":   1,
+	"B":  1,
+	"k":  1 << 10,
+	"K":  1 << 10,
+	"kB": 1 << 10,
+	"KB": 1 << 10,
+	"m":  1 << 20,
+	"M":  1 << 20,
+	"mB": 1 << 20,
+	"MB": 1 << 20,
+	"g":  1 << 30,
+	"G":  1 << 30,
+	"gB": 1 << 30,
+	"GB": 1 << 30,
+	"kb": 1 << 10 / 8,
+	"Kb": 1 << 10 / 8,
+	"mb": 1 << 20 / 8,
+	"Mb": 1 << 20 / 8,
+	"gb": 1 << 30 / 8,
+	"Gb": 1 << 30 / 8,
+}
+
+// ParseSize parses a size or offset as taken by -s and -l: a decimal
+// number, optionally fractional and followed by one of the postfixes k, m,
+// g (or kB, MB, GB) for multiples of 1024 bytes or kb, mb, gb for
+// multiples of 1024 bits, rounded down to the nearest byte; or a 0x
+// prefixed hex number. A leading '-' yields a negative value, which Xxd
+// takes as an offset from the end of the input.
+func ParseSize(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+
+	neg := false
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+	if str == "" {
+		return 0, fmt.Errorf("xxd: invalid size %q", s)
+	}
+
+	var v int64
+	if len(str) > 2 && isPrefix([]byte(str[:2])) {
+		u, err := strconv.ParseUint(str[2:], 16, 63)
+		if err != nil {
+			return 0, fmt.Errorf("xxd: invalid size %q", s)
+		}
+		v = int64(u)
+	} else {
+		i := 0
+		for i < len(str) && (str[i] >= '0' && str[i] <= '9' || str[i] == '.') {
+			i++
+		}
+		mul, ok := sizeUnits[str[i:]]
+		if !ok {
+			return 0, fmt.Errorf("xxd: invalid size %q: unknown unit %q", s, str[i:])
+		}
+		f, err := strconv.ParseFloat(str[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("xxd: invalid size %q", s)
+		}
+		f = math.Floor(f * mul)
+		if f >= math.MaxInt64 {
+			return 0, fmt.Errorf("xxd: invalid size %q: out of range", s)
+		}
+		v = int64(f)
+	}
+
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
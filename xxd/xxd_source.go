@@ -0,0 +1,368 @@
+package xxd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// SourceEmitter describes how a dump is written as an array declaration
+// in some programming language. Every line of values is indented, wrapped
+// in LinePrefix and LineSuffix and ended with LineEnd; in the declaration
+// templates {name} stands for the variable name and {n} for the octet
+// count. See RegisterSourceFormat for adding one.
+type SourceEmitter struct {
+	Lang     string                      // name selecting the format, see SourceFormat
+	Style    func(words []string) string // identifier from the words of a file name, snake_case if nil
+	Keywords string                      // reserved words, space separated
+	Upper    bool                        // identifiers are upper case, a VarName too
+
+	Open  string // declaration up to the first line of values
+	Close string // end of the declaration
+	Empty string // whole declaration when there are no octets, Open+Close if empty
+
+	Indent     string
+	LinePrefix string
+	LineSuffix string
+	LineEnd    string
+	Sep        string // between the values of a line
+
+	// Literal appends the value b, caps being the 16 hex digits to use.
+	// Values are written as 0x89 if nil.
+	Literal func(dst []byte, b byte, caps string) []byte
+	// Decode appends the values in a line of the declaration for
+	// XxdReverse. It is only handed what lies between the last character
+	// of Open and the first of Close, so that the variable name is never
+	// taken for values. The 0x literals are read if nil.
+	Decode func(dst, line []byte) []byte
+}
+
+// emitters maps dump types to the languages they write. A built-in
+// language takes a Dump* constant and an entry here, others are added by
+// RegisterSourceFormat.
+var emitters = map[int]*SourceEmitter{
+	DumpRust: {
+		Lang:     "rust",
+		Style:    screamingCase,
+		Upper:    true, // rustc warns about lower case statics
+		Keywords: "as break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while async await dyn",
+		Open:     "pub static {name}: [u8; {name}_LEN] = [\n",
+		Close:    "];\npub const {name}_LEN: usize = {n};\n",
+		Empty:    "pub static {name}: [u8; {name}_LEN] = [];\npub const {name}_LEN: usize = 0;\n",
+		Indent:   "    ",
+		LineEnd:  ",",
+		Sep:      ", ",
+		Literal:  hexLiteral,
+		Decode:   decodeHexLiterals,
+	},
+	DumpPython: {
+		Lang:       "python",
+		Style:      snakeCase,
+		Keywords:   "False None True and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield",
+		Open:       "{name} = (\n",
+		Close:      ")\n",
+		Empty:      "{name} = b\"\"\n",
+		Indent:     "    ",
+		LinePrefix: "b\"",
+		LineSuffix: "\"",
+		Literal:    escapeLiteral,
+		Decode:     decodeGoString,
+	},
+	DumpJavaScript: {
+		Lang:     "javascript",
+		Style:    camelCase,
+		Keywords: "await break case catch class const continue debugger default delete do else enum export extends false finally for function if import in instanceof let new null return static super switch this throw true try typeof var void while with yield",
+		Open:     "const {name} = new Uint8Array([\n",
+		Close:    "]);\n",
+		Empty:    "const {name} = new Uint8Array(0);\n",
+		Indent:   "  ",
+		LineEnd:  ",",
+		Sep:      ", ",
+		Literal:  hexLiteral,
+		Decode:   decodeHexLiterals,
+	},
+	DumpJava: {
+		Lang:     "java",
+		Style:    screamingCase,
+		Keywords: "abstract assert boolean break byte case catch char class const continue default do double else enum extends false final finally float for goto if implements import instanceof int interface long native new null package private protected public return short static strictfp super switch synchronized this throw throws transient true try void volatile while",
+		Open:     "public static final byte[] {name} = {\n",
+		Close:    "};\n",
+		Empty:    "public static final byte[] {name} = {};\n",
+		Indent:   "    ",
+		LineEnd:  ",",
+		Sep:      ", ",
+		Literal:  javaLiteral,
+		Decode:   decodeHexLiterals,
+	},
+	DumpCSharp: {
+		Lang:     "csharp",
+		Style:    pascalCase,
+		Keywords: "abstract as base bool break byte case catch char checked class const continue decimal default delegate do double else enum event explicit extern false finally fixed float for foreach goto if implicit in int interface internal is lock long namespace new null object operator out override params private protected public readonly ref return sbyte sealed short sizeof stackalloc static string struct switch this throw true try typeof uint ulong unchecked unsafe ushort using virtual void volatile while",
+		Open:     "public static readonly byte[] {name} = {\n",
+		Close:    "};\n",
+		Empty:    "public static readonly byte[] {name} = {};\n",
+		Indent:   "    ",
+		LineEnd:  ",",
+		Sep:      ", ",
+		Literal:  hexLiteral,
+		Decode:   decodeHexLiterals,
+	},
+}
+
+var (
+	emittersMu       sync.RWMutex // guards emitters and nextSourceFormat
+	nextSourceFormat = firstSourceFormat
+)
+
+// firstSourceFormat is the dump type of the first registered language,
+// well clear of the Dump* constants
+const firstSourceFormat = 1 << 10
+
+// RegisterSourceFormat adds the language se describes and returns the
+// dump type writing it, for WithFormat like any of the Dump* constants.
+// se.Lang must not name a language SourceFormat already knows, and Open
+// and Close are required. Changes to se after the call have no effect.
+// Languages are best registered from an init function, before they are
+// dumped in.
+func RegisterSourceFormat(se SourceEmitter) (int, error) {
+	switch {
+	case se.Lang == "":
+		return 0, errors.New("xxd: source format without a language name")
+	case se.Open == "" || se.Close == "":
+		return 0, fmt.Errorf("xxd: source format %q: no Open or Close template", se.Lang)
+	}
+	if se.Style == nil {
+		se.Style = snakeCase
+	}
+	if se.Literal == nil {
+		se.Literal = hexLiteral
+	}
+	if se.Decode == nil {
+		se.Decode = decodeHexLiterals
+	}
+
+	emittersMu.Lock()
+	defer emittersMu.Unlock()
+	if _, ok := lookupSourceFormat(se.Lang); ok {
+		return 0, fmt.Errorf("xxd: source format %q already registered", se.Lang)
+	}
+	t := nextSourceFormat
+	nextSourceFormat++
+	emitters[t] = &se
+	return t, nil
+}
+
+// SourceFormat returns the dump type writing source code in lang, one of
+// c, go, rust, python, javascript, java, csharp or a registered language
+func SourceFormat(lang string) (int, bool) {
+	emittersMu.RLock()
+	defer emittersMu.RUnlock()
+	return lookupSourceFormat(lang)
+}
+
+// lookupSourceFormat is SourceFormat for callers holding emittersMu
+func lookupSourceFormat(lang string) (int, bool) {
+	switch lang {
+	case "c":
+		return DumpCformat, true
+	case "go":
+		return DumpGo, true
+	}
+	for t, se := range emitters {
+		if se.Lang == lang {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// sourceEmitter returns the language dumpType writes, nil for C, Go and
+// dumps that are not source code
+func sourceEmitter(dumpType int) *SourceEmitter {
+	emittersMu.RLock()
+	defer emittersMu.RUnlock()
+	return emitters[dumpType]
+}
+
+// isSource reports whether dumpType writes a declaration in some
+// programming language, which holds back its last line of values
+func isSource(dumpType int) bool {
+	return dumpType == DumpCformat || dumpType == DumpGo || sourceEmitter(dumpType) != nil
+}
+
+// varName returns the identifier declared for the file fname, or for
+// Config.VarName when that is set. File names are split into words at
+// anything but ASCII letters and digits and joined in the language's
+// naming style; stdin without a VarName is called data, as is a file
+// whose words the style turns into nothing. Capitalize upper cases the
+// result like -C does for C, as do languages whose statics are upper case
+// by convention.
+func (se *SourceEmitter) varName(fname string, cfg *Config) string {
+	var name string
+	if cfg.VarName != "" {
+		b := []byte(cfg.VarName)
+		for i := range b {
+			if !isAlnum(b[i]) {
+				b[i] = '_'
+			}
+		}
+		name = string(b)
+	} else {
+		var words []string
+		if fname != "-" {
+			words = strings.FieldsFunc(fname, func(r rune) bool {
+				return r >= 0x80 || !isAlnum(byte(r))
+			})
+		}
+		if len(words) == 0 {
+			words = []string{"data"}
+		}
+		name = se.Style(words)
+	}
+
+	if name == "" {
+		name = "data" // a registered Style that made nothing of the words
+	}
+	if isDigit(name[0]) {
+		name = "_" + name
+	}
+	if cfg.Capitalize || se.Upper {
+		name = strings.ToUpper(name)
+	}
+	for _, k := range strings.Fields(se.Keywords) {
+		if k == name {
+			return name + "_"
+		}
+	}
+	return name
+}
+
+// appendHeader renders the declaration up to the first line of values, or
+// all of it when there are none
+func (se *SourceEmitter) appendHeader(dst []byte, name string, n int64) []byte {
+	if n > 0 {
+		return appendTemplate(dst, se.Open, name, n)
+	}
+	if se.Empty != "" {
+		return appendTemplate(dst, se.Empty, name, n)
+	}
+	dst = appendTemplate(dst, se.Open, name, n)
+	return se.appendFooter(dst, name, n)
+}
+
+func (se *SourceEmitter) appendFooter(dst []byte, name string, n int64) []byte {
+	return appendTemplate(dst, se.Close, name, n)
+}
+
+// appendTemplate expands the {name} and {n} placeholders in tmpl
+func appendTemplate(dst []byte, tmpl, name string, n int64) []byte {
+	r := strings.NewReplacer("{name}", name, "{n}", strconv.FormatInt(n, 10))
+	return append(dst, r.Replace(tmpl)...)
+}
+
+// appendLine renders b as one line of values
+func (se *SourceEmitter) appendLine(dst []byte, b []byte, caps string) []byte {
+	dst = append(dst, se.Indent...)
+	dst = append(dst, se.LinePrefix...)
+	for i := 0; i < len(b); i++ {
+		if i > 0 {
+			dst = append(dst, se.Sep...)
+		}
+		dst = se.Literal(dst, b[i], caps)
+	}
+	dst = append(dst, se.LineSuffix...)
+	dst = append(dst, se.LineEnd...)
+	return append(dst, newLine...)
+}
+
+// sourceParser holds the state of a Decoder reading a dump written by a
+// SourceEmitter: whether the delimiter ending Open, such as the '[' of
+// Rust or the '{' of Java, has been seen and whether the one starting
+// Close has.
+type sourceParser struct {
+	se          *SourceEmitter
+	open, close byte
+	opened      bool
+	closed      bool
+}
+
+func newSourceParser(se *SourceEmitter) *sourceParser {
+	p := &sourceParser{se: se}
+	if s := strings.TrimRightFunc(se.Open, unicode.IsSpace); s != "" {
+		p.open = s[len(s)-1]
+	}
+	if s := strings.TrimLeftFunc(se.Close, unicode.IsSpace); s != "" {
+		p.close = s[0]
+	}
+	return p
+}
+
+// decode appends the values of line lying between the delimiters. The
+// last open delimiter of a line is taken since the declaration may hold
+// more, as in Rust's [u8; N].
+func (p *sourceParser) decode(dst, line []byte) []byte {
+	if p.closed {
+		return dst
+	}
+	if !p.opened {
+		i := bytes.LastIndexByte(line, p.open)
+		if i < 0 {
+			return dst
+		}
+		p.opened, line = true, line[i+1:]
+	}
+	if i := bytes.IndexByte(line, p.close); i >= 0 {
+		p.closed, line = true, line[:i]
+	}
+	return p.se.Decode(dst, line)
+}
+
+// hexLiteral renders b as 0x89
+func hexLiteral(dst []byte, b byte, caps string) []byte {
+	return append(dst, '0', 'x', caps[b>>4], caps[b&0x0f])
+}
+
+// escapeLiteral renders b as the string escape \x89
+func escapeLiteral(dst []byte, b byte, caps string) []byte {
+	return append(dst, '\\', 'x', caps[b>>4], caps[b&0x0f])
+}
+
+// javaLiteral renders b as 0x50, or (byte) 0x89 for octets that do not fit
+// Java's signed byte
+func javaLiteral(dst []byte, b byte, caps string) []byte {
+	if b >= 0x80 {
+		dst = append(dst, "(byte) "...)
+	}
+	return hexLiteral(dst, b, caps)
+}
+
+// snakeCase joins words as logo_v2_png
+func snakeCase(words []string) string {
+	return strings.ToLower(strings.Join(words, "_"))
+}
+
+// screamingCase joins words as LOGO_V2_PNG
+func screamingCase(words []string) string {
+	return strings.ToUpper(strings.Join(words, "_"))
+}
+
+// camelCase joins words as logoV2Png
+func camelCase(words []string) string {
+	s := pascalCase(words)
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// pascalCase joins words as LogoV2Png
+func pascalCase(words []string) string {
+	var sb strings.Builder
+	for _, w := range words {
+		w = strings.ToLower(w)
+		sb.WriteString(strings.ToUpper(w[:1]))
+		sb.WriteString(w[1:])
+	}
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+package xxd
+
+import (
+	"fmt"
+	"io"
+)
+
+// srecWidth returns the number of address bytes, 2, 3 or 4, for an
+// S-record image ending just before end
+func srecWidth(end int64) int {
+	switch {
+	case end <= 1<<16:
+		return 2
+	case end <= 1<<24:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// maxSRecordData is the most data a record with a width byte address
+// holds, its count being a single byte
+func maxSRecordData(width int) int {
+	return 0xff - width - 1
+}
+
+// appendSRecordHeader renders the S0 record carrying fname, which stays
+// empty for stdin and is cut short to fit the record
+func (e *encoder) appendSRecordHeader(dst []byte, fname string) []byte {
+	if fname == "-" {
+		fname = ""
+	}
+	if n := maxSRecordData(2); len(fname) > n {
+		fname = fname[:n]
+	}
+	return appendSRecord(dst, '0', 2, 0, []byte(fname))
+}
+
+// appendSRecord renders a single record of the given type with a width
+// byte address, e.g.
+// S1130000285F245F2212226A000424290008237C2A
+func appendSRecord(dst []byte, typ byte, width int, addr int64, data []byte) []byte {
+	n := byte(width + len(data) + 1)
+	sum := n
+	dst = append(dst, 'S', typ)
+	dst = appendHexByte(dst, n)
+	for i := width - 1; i >= 0; i-- {
+		v := byte(addr >> (8 * i))
+		dst = appendHexByte(dst, v)
+		sum += v
+	}
+	for _, v := range data {
+		dst = appendHexByte(dst, v)
+		sum += v
+	}
+	dst = appendHexByte(dst, ^sum)
+	return append(dst, newLine...)
+}
+
+// appendSRecordData renders b, to be loaded at address off, as S1, S2 or
+// S3 data records depending on width
+func (e *encoder) appendSRecordData(dst []byte, off int64, b []byte, width int) []byte {
+	return appendSRecord(dst, byte('0'+width-1), width, off, b)
+}
+
+// appendSRecordEnd renders the S9, S8 or S7 record ending a file whose
+// data records have width byte addresses, with Config.StartAddress as
+// the entry point
+func (e *encoder) appendSRecordEnd(dst []byte, width int) []byte {
+	var start int64
+	if e.cfg.StartAddress >= 0 {
+		start = e.cfg.StartAddress
+	}
+	return appendSRecord(dst, byte('0'+11-width), width, start, nil)
+}
+
+// remaining returns the number of octets between the position of s and
+// its end, ok is false when s cannot tell
+func remaining(s io.Seeker) (n int64, ok bool) {
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return end - cur, true
+}
+
+// srecParser keeps the state of an S-record file being reversed
+type srecParser struct {
+	records int64 // data records seen, for S5 and S6 counts
+	done    bool  // seen the termination record
+}
+
+// decode appends the data of the record on line, the n-th line of the
+// input, to dst and returns the address it belongs at. ok is false for
+// lines carrying no data.
+func (p *srecParser) decode(dst, line []byte, n int) (off int64, out []byte, ok bool, err error) {
+	line = trimSpace(line)
+	if p.done || len(line) == 0 {
+		return 0, dst, false, nil
+	}
+	if len(line) < 2 || line[0] != 'S' && line[0] != 's' {
+		return 0, dst, false, &DecodeError{n, "record does not start with 'S'"}
+	}
+
+	typ := line[1]
+	start := len(dst)
+	dst, good := decodeHexPairs(dst, line[2:])
+	rec := dst[start:]
+	dst = dst[:start]
+	switch {
+	case !good:
+		return 0, dst, false, &DecodeError{n, "record is not hex digits"}
+	case len(rec) < 2 || len(rec) != int(rec[0])+1:
+		return 0, dst, false, &DecodeError{n, "record length does not match its byte count"}
+	}
+
+	var sum byte
+	for _, v := range rec[:len(rec)-1] {
+		sum += v
+	}
+	if sum = ^sum; sum != rec[len(rec)-1] {
+		return 0, dst, false, &DecodeError{n, fmt.Sprintf("checksum is %02X, should be %02X", rec[len(rec)-1], sum)}
+	}
+
+	var width int
+	switch typ {
+	case '0', '1', '5', '9':
+		width = 2
+	case '2', '6', '8':
+		width = 3
+	case '3', '7':
+		width = 4
+	default:
+		return 0, dst, false, &DecodeError{n, fmt.Sprintf("unknown record type S%c", typ)}
+	}
+	if len(rec) < width+2 {
+		return 0, dst, false, &DecodeError{n, fmt.Sprintf("S%c record is too short for its address", typ)}
+	}
+	for _, v := range rec[1 : width+1] {
+		off = off<<8 | int64(v)
+	}
+	data := rec[width+1 : len(rec)-1]
+
+	switch typ {
+	case '1', '2', '3':
+		p.records++
+		return off, append(dst, data...), true, nil
+	case '5', '6':
+		if off != p.records {
+			return 0, dst, false, &DecodeError{n, fmt.Sprintf("count record says %d data records, found %d", off, p.records)}
+		}
+	case '7', '8', '9':
+		p.done = true
+	}
+	return 0, dst, false, nil
+}
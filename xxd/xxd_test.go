@@ -3,22 +3,32 @@ package xxd_test
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"go/format"
+	"io"
+	"strings"
 
 	"os"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	xxd "github.com/rkbalgi/libxxd/xxd"
 )
 
+const helloFile = "../testdata/hello.txt"
+
 func TestXXD(t *testing.T) {
 
-	fileName := "../testdata/hello.txt"
+	fileName := helloFile
 
 	r, err := os.Open(fileName)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	defer r.Close()
 
 	buf := &bytes.Buffer{}
 	w := bufio.NewWriter(buf)
@@ -29,11 +39,1846 @@ func TestXXD(t *testing.T) {
 		t.Error(err)
 	}
 	w.Flush()
-	fmt.Println(buf.String())
-	expectedLen := 810
+	expectedLen := 676
 	if len(buf.Bytes()) != expectedLen {
 		t.Fatal(fmt.Sprintf("Expected: <%d>, Got: <%d>", expectedLen, len(buf.Bytes())))
 
 	}
 
 }
+
+// dumpHello runs Xxd over testdata/hello.txt with cfg and returns the
+// output. It does not fail the test itself, so goroutines may call it.
+func dumpHello(cfg *xxd.Config) (string, error) {
+	r, err := os.Open(helloFile)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	buf := &bytes.Buffer{}
+	if err := xxd.Xxd(r, buf, "hello.txt", cfg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func TestXXDFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		dumpType int
+		first    string
+		last     string
+		lines    int
+	}{
+		{"binary", xxd.DumpBinary,
+			"00000000: 01101000 01100101 01101100 01101100 01101111 00101100   hello,",
+			"00000090: 01110010 00001010                                       r.",
+			25},
+		{"cformat", xxd.DumpCformat,
+			"unsigned char hello_txt[] = {",
+			"unsigned int hello_txt_len = 146;",
+			16},
+		{"postscript", xxd.DumpPostscript,
+			"68656c6c6f2c20776f726c64212054686973206973207361747572646179",
+			"65723f3f3f3f3f3f3f3f3f3f3f3f3f0a68656c6c6f20776f720a",
+			5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := dumpHello(&xxd.Config{DumpType: tt.dumpType, Columns: -1, Group: -1, Length: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			if len(lines) != tt.lines {
+				t.Fatalf("Expected <%d> lines, Got: <%d>\n%s", tt.lines, len(lines), out)
+			}
+			if lines[0] != tt.first {
+				t.Errorf("Expected first line: <%s>, Got: <%s>", tt.first, lines[0])
+			}
+			if lines[len(lines)-1] != tt.last {
+				t.Errorf("Expected last line: <%s>, Got: <%s>", tt.last, lines[len(lines)-1])
+			}
+		})
+	}
+}
+
+func TestXXDConcurrent(t *testing.T) {
+	want := make(map[int]string)
+	for _, dt := range []int{xxd.DumpHex, xxd.DumpBinary, xxd.DumpCformat, xxd.DumpPostscript} {
+		out, err := dumpHello(&xxd.Config{DumpType: dt, Columns: -1, Group: -1, Length: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[dt] = out
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		dt := i % len(want)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := dumpHello(&xxd.Config{DumpType: dt, Columns: -1, Group: -1, Length: -1})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got != want[dt] {
+				t.Errorf("DumpType %d: concurrent output differs from serial output", dt)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDumper(t *testing.T) {
+	data, err := os.ReadFile(helloFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nul lines either side of the text so autoskip state has to survive
+	// between writes
+	data = append(append(make([]byte, 64), data...), make([]byte, 80)...)
+
+	for _, dt := range []int{xxd.DumpHex, xxd.DumpBinary, xxd.DumpCformat, xxd.DumpPostscript} {
+		cfg := &xxd.Config{DumpType: dt, AutoSkip: true, Columns: -1, Group: -1, Length: -1}
+
+		// a Dumper names C arrays data
+		want := &bytes.Buffer{}
+		if err := xxd.Xxd(bytes.NewReader(data), want, "data", cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, chunk := range []int{1, 3, 7, 16, 100} {
+			got := &bytes.Buffer{}
+			d := xxd.NewDumper(got, cfg)
+			for b := data; len(b) > 0; {
+				k := chunk
+				if k > len(b) {
+					k = len(b)
+				}
+				if n, err := d.Write(b[:k]); n != k || err != nil {
+					t.Fatalf("Write: %d, %v", n, err)
+				}
+				b = b[k:]
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("DumpType %d, chunk %d: Expected:\n%s\nGot:\n%s", dt, chunk, want, got)
+			}
+		}
+	}
+}
+
+func TestDumperClose(t *testing.T) {
+	buf := &bytes.Buffer{}
+	d := xxd.NewDumper(buf, &xxd.Config{DumpType: xxd.DumpCformat, Columns: 4, Group: -1, Length: -1})
+	d.Write([]byte("hell"))
+	if got := buf.String(); got != "" {
+		t.Errorf("Expected nothing before the last line is known, Got: <%s>", got)
+	}
+	d.Write([]byte("o"))
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "unsigned char data[] = {\n  0x68, 0x65, 0x6c, 0x6c,\n  0x6f\n};\nunsigned int data_len = 5;\n"; got != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, got)
+	}
+	if _, err := d.Write([]byte("x")); err != xxd.ErrClosed {
+		t.Errorf("Expected ErrClosed, Got: %v", err)
+	}
+
+	buf.Reset()
+	if err := xxd.Xxd(strings.NewReader("hello"), buf, "hello.txt", &xxd.Config{DumpType: xxd.DumpCformat, Columns: -1, Group: -1, Length: -1}); err != nil {
+		t.Fatal(err)
+	}
+	want := "unsigned char hello_txt[] = {\n  0x68, 0x65, 0x6c, 0x6c, 0x6f\n};\nunsigned int hello_txt_len = 5;\n"
+	if buf.String() != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, buf.String())
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	data, err := os.ReadFile(helloFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(append(data, make([]byte, 70)...), 0xde, 0xad, 0xbe, 0xef)
+
+	for _, dt := range []int{xxd.DumpHex, xxd.DumpBinary, xxd.DumpCformat, xxd.DumpPostscript, xxd.DumpOctal, xxd.DumpDecimal, xxd.DumpSigned} {
+		cfg := &xxd.Config{DumpType: dt, Columns: -1, Group: -1, Length: -1}
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(bytes.NewReader(data), dump, "data.bin", cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		// iotest.TestReader exercises small and odd sized reads
+		if err := iotest.TestReader(xxd.NewDecoder(bytes.NewReader(dump.Bytes()), cfg), data); err != nil {
+			t.Errorf("DumpType %d: %v", dt, err)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), data) {
+			t.Errorf("DumpType %d: Expected: <%x>, Got: <%x>", dt, data, got.Bytes())
+		}
+	}
+}
+
+func TestDecoderSystemXXD(t *testing.T) {
+	// captured from vim's xxd, including a line whose character column
+	// looks like hex
+	dump := "00000000: 6465 6164 6265 6566  deadbeef\n" +
+		"00000008: 6361 6665  cafe\n"
+	b, err := io.ReadAll(xxd.NewDecoder(strings.NewReader(dump), xxd.NewConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "deadbeefcafe" {
+		t.Errorf("Expected: <deadbeefcafe>, Got: <%s>", b)
+	}
+}
+
+func TestDecoderBackwards(t *testing.T) {
+	// vim's xxd -r cannot seek backwards on a pipe either
+	dump := "00000010: 4142  AB\n" +
+		"00000000: 4344  CD\n"
+	b, err := io.ReadAll(xxd.NewDecoder(strings.NewReader(dump), xxd.NewConfig()))
+	var decErr *xxd.DecodeError
+	if !errors.As(err, &decErr) || decErr.Line != 2 || !strings.Contains(decErr.Reason, "XxdPatch") {
+		t.Errorf("Expected: <line 2 error>, Got: <%v>", err)
+	}
+	if want := "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00AB"; string(b) != want {
+		t.Errorf("Expected: <%q>, Got: <%q>", want, b)
+	}
+}
+
+func TestXxdPatch(t *testing.T) {
+	dump := "00000002: 4142\n" +
+		"00000008: 4344\n"
+
+	tests := []struct {
+		fillGaps bool
+		want     string
+	}{
+		{false, "01AB4567CD"},
+		{true, "\x00\x00AB\x00\x00\x00\x00CD"},
+	}
+
+	for _, tt := range tests {
+		f, err := os.CreateTemp(t.TempDir(), "patch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString("0123456789")
+
+		cfg := xxd.NewConfig()
+		cfg.FillGaps = tt.fillGaps
+		if err := xxd.XxdPatch(strings.NewReader(dump), f, cfg); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		got, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("FillGaps %v: Expected: <%q>, Got: <%q>", tt.fillGaps, tt.want, got)
+		}
+	}
+}
+
+func TestDecoderAutoSkip(t *testing.T) {
+	data := append(make([]byte, 200), "hello"...)
+	cfg := &xxd.Config{AutoSkip: true, Columns: -1, Group: -1, Length: -1}
+
+	dump := &bytes.Buffer{}
+	if err := xxd.Xxd(bytes.NewReader(data), dump, "-", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dump.String(), "*\n") {
+		t.Fatalf("Expected nul lines to be skipped:\n%s", dump)
+	}
+
+	got, err := io.ReadAll(xxd.NewDecoder(dump, cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected: <%x>, Got: <%x>", data, got)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	cfg := xxd.NewConfig()
+	want := &xxd.Config{DumpType: xxd.DumpHex, Columns: -1, Group: -1, Length: -1, StartAddress: -1}
+	if *cfg != *want {
+		t.Errorf("Expected: <%+v>, Got: <%+v>", want, cfg)
+	}
+
+	cfg = xxd.NewConfig(xxd.WithFormat(xxd.DumpBinary), xxd.WithColumns(8), xxd.WithGroup(4),
+		xxd.WithLength(10), xxd.WithSeek("1k"), xxd.WithUpper, xxd.WithAutoSkip, xxd.WithBars, xxd.WithEbcdic)
+	want = &xxd.Config{DumpType: xxd.DumpBinary, Columns: 8, Group: 4, Length: 10, Seek: "1k",
+		Upper: true, AutoSkip: true, Bars: true, Ebcdic: true, StartAddress: -1}
+	if *cfg != *want {
+		t.Errorf("Expected: <%+v>, Got: <%+v>", want, cfg)
+	}
+}
+
+func TestXxdWith(t *testing.T) {
+	dump := &bytes.Buffer{}
+	if err := xxd.XxdWith(strings.NewReader("hello"), dump, "-", xxd.WithUpper, xxd.WithColumns(4), xxd.WithBars); err != nil {
+		t.Fatal(err)
+	}
+	want := "00000000: 6865 6C6C   |hell|\n00000004: 6F          |o|\n"
+	if dump.String() != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, dump)
+	}
+
+	got := &bytes.Buffer{}
+	if err := xxd.XxdReverseWith(dump, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "hello" {
+		t.Errorf("Expected: <hello>, Got: <%s>", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg   *xxd.Config
+		field string
+	}{
+		{xxd.NewConfig(), ""},
+		{xxd.NewConfig(xxd.WithGroup(0)), ""},
+		{xxd.NewConfig(xxd.WithLength(0)), ""},
+		{xxd.NewConfig(xxd.WithColumns(4), xxd.WithGroup(4)), ""},
+		{xxd.NewConfig(xxd.WithColumns(0)), "Columns"},
+		{xxd.NewConfig(xxd.WithColumns(-2)), "Columns"},
+		{xxd.NewConfig(xxd.WithGroup(-2)), "Group"},
+		{xxd.NewConfig(xxd.WithColumns(4), xxd.WithGroup(5)), "Group"},
+		{xxd.NewConfig(xxd.WithGroup(17)), "Group"},
+		{xxd.NewConfig(xxd.WithLength(-2)), "Length"},
+		{xxd.NewConfig(xxd.WithFormat(42)), "DumpType"},
+		{&xxd.Config{}, "Columns"},
+	}
+
+	for _, tt := range tests {
+		err := tt.cfg.Validate()
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", tt.cfg, err)
+			}
+			continue
+		}
+
+		var cfgErr *xxd.ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("%+v: Expected a *ConfigError, Got: %v", tt.cfg, err)
+			continue
+		}
+		if cfgErr.Field != tt.field {
+			t.Errorf("%+v: Expected field <%s>, Got: <%s>", tt.cfg, tt.field, cfgErr.Field)
+		}
+
+		// every entry point refuses the config up front
+		if err := xxd.Xxd(strings.NewReader("hello"), io.Discard, "-", tt.cfg); !errors.As(err, &cfgErr) {
+			t.Errorf("Xxd: Expected a *ConfigError, Got: %v", err)
+		}
+		if err := xxd.XxdReverse(strings.NewReader("00000000: 6865"), io.Discard, tt.cfg); !errors.As(err, &cfgErr) {
+			t.Errorf("XxdReverse: Expected a *ConfigError, Got: %v", err)
+		}
+		if _, err := xxd.NewDumper(io.Discard, tt.cfg).Write([]byte("hello")); !errors.As(err, &cfgErr) {
+			t.Errorf("Dumper: Expected a *ConfigError, Got: %v", err)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  bool
+	}{
+		{"0", 0, false},
+		{"100", 100, false},
+		{"+100", 100, false},
+		{"-100", -100, false},
+		{"2k", 2048, false},
+		{"2K", 2048, false},
+		{"1.5kB", 1536, false},
+		{"1M", 1 << 20, false},
+		{"1GB", 1 << 30, false},
+		{"1kb", 128, false},
+		{"3mb", 3 << 17, false},
+		{"0.001kb", 0, false},
+		{"0x10", 16, false},
+		{"0XfF", 255, false},
+		{"-0x10", -16, false},
+		{"", 0, true},
+		{"-", 0, true},
+		{"k", 0, true},
+		{"10x", 0, true},
+		{"0xg", 0, true},
+		{"1..5", 0, true},
+		{"99999999999G", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := xxd.ParseSize(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: Expected: <%d>, Got: <%d>", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestXXDSeek(t *testing.T) {
+	data := []byte("0123456789abcdef")
+
+	tests := []struct {
+		seek string
+		want string
+	}{
+		{"10", "abcdef"},
+		{"0xc", "cdef"},
+		{"-4", "cdef"},
+		{"100", ""},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(xxd.WithSeek(tt.seek), xxd.WithFormat(xxd.DumpPostscript))
+		want := ""
+		if tt.want != "" {
+			want = fmt.Sprintf("%x\n", tt.want)
+		}
+
+		// bytes.Reader is seeked, OneByteReader has to be read past
+		for _, r := range []io.Reader{bytes.NewReader(data), iotest.OneByteReader(bytes.NewReader(data))} {
+			buf := &bytes.Buffer{}
+			err := xxd.Xxd(r, buf, "-", cfg)
+			if _, seekable := r.(io.Seeker); tt.seek[0] == '-' && !seekable {
+				if err == nil {
+					t.Errorf("%q: Expected an error seeking from the end of a stream", tt.seek)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != want {
+				t.Errorf("%q: Expected: <%s>, Got: <%s>", tt.seek, want, buf)
+			}
+		}
+	}
+}
+
+func TestXXDLength(t *testing.T) {
+	data, err := os.ReadFile(helloFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dt := range []int{xxd.DumpHex, xxd.DumpBinary, xxd.DumpCformat, xxd.DumpPostscript} {
+		for _, length := range []int64{0, 1, 5, 16, 20, 100, 146, 1 << 40} {
+			want := &bytes.Buffer{}
+			n := length
+			if n > int64(len(data)) {
+				n = int64(len(data))
+			}
+			if err := xxd.Xxd(bytes.NewReader(data[:n]), want, "hello.txt", xxd.NewConfig(xxd.WithFormat(dt))); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := xxd.NewConfig(xxd.WithFormat(dt), xxd.WithLength(length))
+			got := &bytes.Buffer{}
+			if err := xxd.Xxd(bytes.NewReader(data), got, "hello.txt", cfg); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("DumpType %d, Length %d: Expected:\n%s\nGot:\n%s", dt, length, want, got)
+			}
+
+			// the Dumper enforces the budget across writes
+			stream := &bytes.Buffer{}
+			if err := xxd.Xxd(bytes.NewReader(data[:n]), stream, "data", xxd.NewConfig(xxd.WithFormat(dt))); err != nil {
+				t.Fatal(err)
+			}
+			got.Reset()
+			d := xxd.NewDumper(got, cfg)
+			for b := data; len(b) > 0; b = b[1:] {
+				d.Write(b[:1])
+			}
+			d.Close()
+			if got.String() != stream.String() {
+				t.Errorf("Dumper DumpType %d, Length %d: Expected:\n%s\nGot:\n%s", dt, length, stream, got)
+			}
+
+			rev, err := io.ReadAll(xxd.NewDecoder(bytes.NewReader(want.Bytes()), cfg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rev, data[:n]) {
+				t.Errorf("Decoder DumpType %d, Length %d: Expected: <%x>, Got: <%x>", dt, length, data[:n], rev)
+			}
+		}
+	}
+
+	// short lines keep the character column where a full line has it
+	got := &bytes.Buffer{}
+	if err := xxd.Xxd(bytes.NewReader(data), got, "-", xxd.NewConfig(xxd.WithLength(5))); err != nil {
+		t.Fatal(err)
+	}
+	if want := "00000000: 6865 6c6c 6f                              hello\n"; got.String() != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, got)
+	}
+
+	// the reverse budget cuts into the middle of a line
+	rev := &bytes.Buffer{}
+	if err := xxd.XxdReverse(got, rev, xxd.NewConfig(xxd.WithLength(3))); err != nil {
+		t.Fatal(err)
+	}
+	if rev.String() != "hel" {
+		t.Errorf("Expected: <hel>, Got: <%s>", rev)
+	}
+}
+
+func TestXXDLittleEndian(t *testing.T) {
+	tests := []struct {
+		opts []xxd.Option
+		want string
+	}{
+		{nil,
+			"00000000: 6c6c6568 77202c6f 646c726f 68542021   hello, world! Th\n" +
+				"00000010:       65                              e\n"},
+		{[]xxd.Option{xxd.WithGroup(2), xxd.WithUpper},
+			"00000000: 6568 6C6C 2C6F 7720 726F 646C 2021 6854   hello, world! Th\n" +
+				"00000010:   65                                      e\n"},
+		{[]xxd.Option{xxd.WithGroup(8), xxd.WithColumns(8)},
+			"00000000: 77202c6f6c6c6568   hello, w\n" +
+				"00000008: 68542021646c726f   orld! Th\n" +
+				"00000010:               65   e\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(append(tt.opts, xxd.WithFormat(xxd.DumpLittleEndian))...)
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader("hello, world! The"), dump, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if dump.String() != tt.want {
+			t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, dump)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != "hello, world! The" {
+			t.Errorf("Expected: <hello, world! The>, Got: <%s>", got)
+		}
+	}
+
+	var cfgErr *xxd.ConfigError
+	err := xxd.NewConfig(xxd.WithFormat(xxd.DumpLittleEndian), xxd.WithGroup(3)).Validate()
+	if !errors.As(err, &cfgErr) || cfgErr.Field != "Group" {
+		t.Errorf("Expected a Group error for 3 octet words, Got: %v", err)
+	}
+}
+
+func TestXXDDisplayOffset(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+
+	tests := []struct {
+		opts []xxd.Option
+		seek int
+		want []string // offsets of the lines
+	}{
+		{[]xxd.Option{xxd.WithDisplayOffset(0x1000)}, 0, []string{"00001000", "00001010", "00001020"}},
+		{[]xxd.Option{xxd.WithSeek("0x10")}, 16, []string{"00000000", "00000010"}},
+		{[]xxd.Option{xxd.WithSeek("0x10"), xxd.WithSeekOffsets}, 16, []string{"00000010", "00000020"}},
+		{[]xxd.Option{xxd.WithSeek("-6"), xxd.WithSeekOffsets, xxd.WithDisplayOffset(0x100)}, 30, []string{"0000011e"}},
+		{[]xxd.Option{xxd.WithDecimal, xxd.WithDisplayOffset(100)}, 0, []string{"00000100", "00000116", "00000132"}},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(bytes.NewReader(data), dump, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		var offsets []string
+		for _, l := range strings.Split(strings.TrimSuffix(dump.String(), "\n"), "\n") {
+			offsets = append(offsets, l[:strings.Index(l, ":")])
+		}
+		if strings.Join(offsets, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%+v: Expected offsets: <%v>, Got: <%v>", cfg, tt.want, offsets)
+		}
+
+		// patching with the same Config puts the bytes back where they
+		// were read from
+		f, err := os.CreateTemp(t.TempDir(), "patch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(bytes.Repeat([]byte("-"), len(data)))
+		if err := xxd.XxdPatch(dump, f, cfg); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		got, _ := os.ReadFile(f.Name())
+		want := strings.Repeat("-", tt.seek) + string(data[tt.seek:])
+		if string(got) != want {
+			t.Errorf("%+v: Expected: <%s>, Got: <%s>", cfg, want, got)
+		}
+	}
+}
+
+func TestXXDOffsetWidth(t *testing.T) {
+	tests := []struct {
+		opts []xxd.Option
+		want string
+	}{
+		{nil, "00000000: 6869"},
+		{[]xxd.Option{xxd.WithDisplayOffset(0xfffffffe)}, "fffffffe: 6869"},
+		{[]xxd.Option{xxd.WithDisplayOffset(0x100000000)}, "100000000: 6869"},
+		{[]xxd.Option{xxd.WithOffsetWidth(4)}, "0000: 6869"},
+		{[]xxd.Option{xxd.WithOffsetWidth(12), xxd.WithDisplayOffset(0x100000000)}, "000100000000: 6869"},
+		{[]xxd.Option{xxd.WithOffsetWidth(2), xxd.WithDisplayOffset(0x1234)}, "1234: 6869"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader("hi"), dump, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(dump.String(), tt.want+" ") {
+			t.Errorf("Expected prefix: <%s>, Got: <%s>", tt.want, dump)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != "hi" {
+			t.Errorf("%s: Expected: <hi>, Got: <%q>", tt.want, got)
+		}
+	}
+}
+
+func TestXXDCinclude(t *testing.T) {
+	tests := []struct {
+		fname string
+		opts  []xxd.Option
+		want  string
+	}{
+		{"assets/logo-v2.png", nil, "unsigned char assets_logo_v2_png[] = {\n  0x68, 0x69\n};\nunsigned int assets_logo_v2_png_len = 2;\n"},
+		{"3d.bin", nil, "unsigned char _3d_bin[] = {\n  0x68, 0x69\n};\nunsigned int _3d_bin_len = 2;\n"},
+		{"naïve.bin", nil, "unsigned char na_ve_bin[] = {\n  0x68, 0x69\n};\nunsigned int na_ve_bin_len = 2;\n"},
+		{"3d.bin", []xxd.Option{xxd.WithCapitalize}, "unsigned char _3D_BIN[] = {\n  0x68, 0x69\n};\nunsigned int _3D_BIN_LEN = 2;\n"},
+		{"-", []xxd.Option{xxd.WithVarName("blob")}, "unsigned char blob[] = {\n  0x68, 0x69\n};\nunsigned int blob_len = 2;\n"},
+		{"x.bin", []xxd.Option{xxd.WithVarName("my-blob"), xxd.WithStatic, xxd.WithConst},
+			"static const unsigned char my_blob[] = {\n  0x68, 0x69\n};\nstatic const unsigned int my_blob_len = 2;\n"},
+		{"x.bin", []xxd.Option{xxd.WithAlign(16), xxd.WithConst},
+			"alignas(16) const unsigned char x_bin[] = {\n  0x68, 0x69\n};\nconst unsigned int x_bin_len = 2;\n"},
+		{"-", nil, "  0x68, 0x69\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(append(tt.opts, xxd.WithFormat(xxd.DumpCformat))...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader("hi"), got, tt.fname, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: Expected: <%s>, Got: <%s>", tt.fname, tt.want, got)
+		}
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpCformat), xxd.WithAlign(12))
+	var cfgErr *xxd.ConfigError
+	if err := cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "Align" {
+		t.Errorf("Expected: <Align error>, Got: <%v>", err)
+	}
+}
+
+func TestXXDGo(t *testing.T) {
+	tests := []struct {
+		fname string
+		input string
+		opts  []xxd.Option
+		want  string
+	}{
+		{"assets/logo.png", "hi", nil,
+			"package main\n\nvar assetsLogoPNG = []byte{\n\t0x68, 0x69,\n}\n"},
+		{"3d-model.bin", "hi", []xxd.Option{xxd.WithCapitalize, xxd.WithPackage("blobs")},
+			"package blobs\n\nvar _3dModelBIN = []byte{\n\t0x68, 0x69,\n}\n"},
+		{"-", "hi", []xxd.Option{xxd.WithVarName("Logo"), xxd.WithColumns(1)},
+			"package main\n\nvar Logo = []byte{\n\t0x68,\n\t0x69,\n}\n"},
+		{"-", "", nil, "package main\n\nvar data = []byte{}\n"},
+		{"-", "hi\xff", []xxd.Option{xxd.WithGoString, xxd.WithUpper, xxd.WithColumns(2)},
+			"package main\n\nconst data = \"\" +\n\t\"\\x68\\x69\" +\n\t\"\\xFF\"\n"},
+		{"-", "", []xxd.Option{xxd.WithGoString}, "package main\n\nconst data = \"\"\n"},
+		{"-", "hi", []xxd.Option{xxd.WithVarName("type")}, "package main\n\nvar type_ = []byte{\n\t0x68, 0x69,\n}\n"},
+		{"fw_0x10.bin", "hi\x10", nil, "package main\n\nvar fw0x10BIN = []byte{\n\t0x68, 0x69, 0x10,\n}\n"},
+		{"fw_0x10.bin", "hi\x10", []xxd.Option{xxd.WithGoString, xxd.WithPackage("fw0x10")},
+			"package fw0x10\n\nconst fw0x10BIN = \"\" +\n\t\"\\x68\\x69\\x10\"\n"},
+		{"e0x1.bin", "", nil, "package main\n\nvar e0x1BIN = []byte{}\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(append(tt.opts, xxd.WithFormat(xxd.DumpGo))...)
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(tt.input), dump, tt.fname, cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		header, body, _ := strings.Cut(dump.String(), "\n\n")
+		if !strings.HasPrefix(header, "// Code generated ") || !strings.HasSuffix(header, " DO NOT EDIT.") {
+			t.Errorf("Expected: <// Code generated ... DO NOT EDIT.>, Got: <%s>", header)
+		}
+		if body != tt.want {
+			t.Errorf("%s: Expected: <%s>, Got: <%s>", tt.fname, tt.want, body)
+		}
+		if src, err := format.Source(dump.Bytes()); err != nil || !bytes.Equal(src, dump.Bytes()) {
+			t.Errorf("%s: not gofmt clean (%v): <%s>", tt.fname, err, dump)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.input {
+			t.Errorf("%s: Expected: <%q>, Got: <%q>", tt.fname, tt.input, got)
+		}
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpGo), xxd.WithPackage("my-pkg"))
+	var cfgErr *xxd.ConfigError
+	if err := cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "Package" {
+		t.Errorf("Expected: <Package error>, Got: <%v>", err)
+	}
+}
+
+func TestXXDSource(t *testing.T) {
+	tests := []struct {
+		lang  string
+		fname string
+		input string
+		opts  []xxd.Option
+		want  string
+	}{
+		{"rust", "assets/logo-v2.png", "hi\x89", nil,
+			"pub static ASSETS_LOGO_V2_PNG: [u8; ASSETS_LOGO_V2_PNG_LEN] = [\n    0x68, 0x69, 0x89,\n];\npub const ASSETS_LOGO_V2_PNG_LEN: usize = 3;\n"},
+		{"rust", "-", "", nil,
+			"pub static DATA: [u8; DATA_LEN] = [];\npub const DATA_LEN: usize = 0;\n"},
+		{"rust", "-", "hi", []xxd.Option{xxd.WithVarName("logo")},
+			"pub static LOGO: [u8; LOGO_LEN] = [\n    0x68, 0x69,\n];\npub const LOGO_LEN: usize = 2;\n"},
+		{"python", "3d.bin", "hi\x89", []xxd.Option{xxd.WithColumns(2), xxd.WithUpper},
+			"_3d_bin = (\n    b\"\\x68\\x69\"\n    b\"\\x89\"\n)\n"},
+		{"python", "-", "", []xxd.Option{xxd.WithVarName("class")}, "class_ = b\"\"\n"},
+		{"javascript", "logo.png", "hi\x89", []xxd.Option{xxd.WithUpper},
+			"const logoPng = new Uint8Array([\n  0x68, 0x69, 0x89,\n]);\n"},
+		{"java", "logo.png", "hi\x89", nil,
+			"public static final byte[] LOGO_PNG = {\n    0x68, 0x69, (byte) 0x89,\n};\n"},
+		{"csharp", "logo.png", "hi\x89", []xxd.Option{xxd.WithColumns(1)},
+			"public static readonly byte[] LogoPng = {\n    0x68,\n    0x69,\n    0x89,\n};\n"},
+		{"csharp", "-", "hi", []xxd.Option{xxd.WithVarName("blob"), xxd.WithCapitalize},
+			"public static readonly byte[] BLOB = {\n    0x68, 0x69,\n};\n"},
+		{"rust", "fw_0x10.bin", "hi\x10", nil,
+			"pub static FW_0X10_BIN: [u8; FW_0X10_BIN_LEN] = [\n    0x68, 0x69, 0x10,\n];\npub const FW_0X10_BIN_LEN: usize = 3;\n"},
+		{"rust", "e0x1.bin", "", nil,
+			"pub static E0X1_BIN: [u8; E0X1_BIN_LEN] = [];\npub const E0X1_BIN_LEN: usize = 0;\n"},
+		{"python", "fw_0x10.bin", "hi\x10", nil, "fw_0x10_bin = (\n    b\"\\x68\\x69\\x10\"\n)\n"},
+		{"javascript", "fw_0x10.bin", "hi\x10", nil,
+			"const fw0x10Bin = new Uint8Array([\n  0x68, 0x69, 0x10,\n]);\n"},
+		{"java", "fw_0x10.bin", "hi\x10", nil,
+			"public static final byte[] FW_0X10_BIN = {\n    0x68, 0x69, 0x10,\n};\n"},
+		{"csharp", "fw_0x10.bin", "hi\x10", nil,
+			"public static readonly byte[] Fw0x10Bin = {\n    0x68, 0x69, 0x10,\n};\n"},
+		{"csharp", "e0x1.bin", "", nil, "public static readonly byte[] E0x1Bin = {};\n"},
+	}
+
+	for _, tt := range tests {
+		dumpType, ok := xxd.SourceFormat(tt.lang)
+		if !ok {
+			t.Fatalf("%s: unknown language", tt.lang)
+		}
+		cfg := xxd.NewConfig(append(tt.opts, xxd.WithFormat(dumpType))...)
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(tt.input), dump, tt.fname, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if dump.String() != tt.want {
+			t.Errorf("%s: Expected: <%s>, Got: <%s>", tt.lang, tt.want, dump)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.input {
+			t.Errorf("%s: Expected: <%q>, Got: <%q>", tt.lang, tt.input, got)
+		}
+	}
+
+	if _, ok := xxd.SourceFormat("cobol"); ok {
+		t.Errorf("Expected: <no format for cobol>")
+	}
+}
+
+func TestRegisterSourceFormat(t *testing.T) {
+	zig, err := xxd.RegisterSourceFormat(xxd.SourceEmitter{
+		Lang:     "zig",
+		Keywords: "const var fn",
+		Open:     "pub const {name} = [_]u8{\n",
+		Close:    "};\n",
+		Indent:   "    ",
+		LineEnd:  ",",
+		Sep:      ", ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := xxd.SourceFormat("zig"); !ok || got != zig {
+		t.Errorf("Expected: <%d>, Got: <%d>", zig, got)
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(zig), xxd.WithColumns(2))
+	dump := &bytes.Buffer{}
+	if err := xxd.Xxd(strings.NewReader("hi\x89"), dump, "logo.png", cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := "pub const logo_png = [_]u8{\n    0x68, 0x69,\n    0x89,\n};\n"
+	if dump.String() != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, dump)
+	}
+	got := &bytes.Buffer{}
+	if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "hi\x89" {
+		t.Errorf("Expected: <%q>, Got: <%q>", "hi\x89", got)
+	}
+
+	dump.Reset()
+	if err := xxd.Xxd(strings.NewReader("hi\x10"), dump, "fw_0x10.bin", cfg); err != nil {
+		t.Fatal(err)
+	}
+	got.Reset()
+	if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "hi\x10" {
+		t.Errorf("Expected: <%q>, Got: <%q>", "hi\x10", got)
+	}
+
+	blank, err := xxd.RegisterSourceFormat(xxd.SourceEmitter{
+		Lang:  "blank",
+		Style: func([]string) string { return "" },
+		Open:  "{name} = [\n",
+		Close: "]\n",
+		Sep:   ", ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump.Reset()
+	if err := xxd.Xxd(strings.NewReader("hi"), dump, "logo.png", xxd.NewConfig(xxd.WithFormat(blank))); err != nil {
+		t.Fatal(err)
+	}
+	if want := "data = [\n0x68, 0x69\n]\n"; dump.String() != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, dump)
+	}
+
+	// only one of the registrations racing for a name may get it
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := xxd.RegisterSourceFormat(xxd.SourceEmitter{Lang: "odin", Open: "{", Close: "}"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	registered := 0
+	for err := range errs {
+		if err == nil {
+			registered++
+		}
+	}
+	if registered != 1 {
+		t.Errorf("Expected: <1 odin registration>, Got: <%d>", registered)
+	}
+
+	for _, se := range []xxd.SourceEmitter{
+		{Lang: "zig", Open: "{", Close: "}"},
+		{Lang: "rust", Open: "{", Close: "}"},
+		{Lang: "go", Open: "{", Close: "}"},
+		{Lang: "odin", Open: "{", Close: "}"},
+		{Lang: "fortran", Open: "{"},
+		{Open: "{", Close: "}"},
+	} {
+		if _, err := xxd.RegisterSourceFormat(se); err == nil {
+			t.Errorf("Expected: <error registering %q>, Got: <nil>", se.Lang)
+		}
+	}
+}
+
+func TestDecoderCinclude(t *testing.T) {
+	tests := []struct {
+		dump string
+		want string
+	}{
+		{"unsigned char hi[] = {\n  0x68, 0x69\n};\nunsigned int hi_len = 2;\n", "hi"},
+		{"  0x68, 0x69\n", "hi"},
+		{"unsigned char x[] = {0x0, 0xA, 255, 010, 0x41u,};\n", "\x00\x0a\xff\x08A"},
+		{"#include <stdalign.h>\n/* hi\n * there 0x41 */\nalignas(16) static const unsigned char x[2] = { // 0x42\n  104, /* 0x43 */ 105\n};\nstatic const unsigned int x_len = 2;\n", "hi"},
+		{"unsigned int X_LEN = 1;\nunsigned char X[] = {\n  0x68 };", "h"},
+		{"unsigned char x[] = {};\nunsigned int x_len = 0;\n", ""},
+		{"unsigned char x[] = {017, 0, 0XfFU, 10ul, 0x1LLu, 2Ul};\n", "\x0f\x00\xff\x0a\x01\x02"},
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpCformat))
+	for _, tt := range tests {
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(strings.NewReader(tt.dump), got, cfg); err != nil {
+			t.Errorf("%q: %v", tt.dump, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.want, got)
+		}
+	}
+
+	bad := []struct {
+		dump string
+		line int
+	}{
+		{"unsigned char hi[] = {\n  0x68, 0x69\n};\nunsigned int hi_len = 3;\n", 4},
+		{"unsigned char hi[] = {\n  0x68 0x69\n};\n", 2},
+		{"unsigned char hi[] = {\n  0x68,\n  0x100\n};\n", 3},
+		{"unsigned char hi[] = {\n  0x68, FOO\n};\n", 2},
+		{"unsigned char hi[] = {\n  0x68, 0x69\n", 2},
+		{"unsigned char hi[] = {\n  0x68 /* 0x69\n};\n", 3},
+		{"unsigned char a[] = {0x68};\nunsigned char b[] = {0x69};\n", 2},
+		// Go literals C does not have
+		{"unsigned char hi[] = {\n  0b1010\n};\n", 2},
+		{"unsigned char hi[] = {\n  0o17\n};\n", 2},
+		{"unsigned char hi[] = {\n  1_0\n};\n", 2},
+		{"unsigned char hi[] = {\n  0x\n};\n", 2},
+		{"unsigned char hi[] = {\n  08\n};\n", 2},
+		{"unsigned char hi[] = {\n  0x1g\n};\n", 2},
+		{"unsigned char hi[] = {\n  1lul\n};\n", 2},
+		{"unsigned char hi[] = {\n  1lL\n};\n", 2},
+	}
+	for _, tt := range bad {
+		err := xxd.XxdReverse(strings.NewReader(tt.dump), io.Discard, cfg)
+		var decErr *xxd.DecodeError
+		if !errors.As(err, &decErr) || decErr.Line != tt.line {
+			t.Errorf("%q: Expected: <error on line %d>, Got: <%v>", tt.dump, tt.line, err)
+		}
+	}
+}
+
+func TestXXDIntelHex(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  []xxd.Option
+		want  string
+	}{
+		{"", nil, ":00000001FF\n"},
+		{"hello", nil, ":0500000068656C6C6FE7\n:00000001FF\n"},
+		{"hello", []xxd.Option{xxd.WithColumns(2), xxd.WithStartAddress(0x8000)},
+			":02000000686531\n:020002006C6C24\n:010004006F8C\n:040000050000800077\n:00000001FF\n"},
+		// records are split at 64 KiB and the upper address follows
+		{"hello", []xxd.Option{xxd.WithDisplayOffset(0x1fffe)},
+			":020000040001F9\n:02FFFE00686534\n:020000040002F8\n:030000006C6C6FB6\n:00000001FF\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(append(tt.opts, xxd.WithFormat(xxd.DumpIntelHex))...)
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(tt.input), dump, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if dump.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, dump)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.input {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.input, got)
+		}
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpIntelHex), xxd.WithDisplayOffset(0xfffffffe))
+	if err := xxd.Xxd(strings.NewReader("hello"), io.Discard, "-", cfg); err != xxd.ErrAddressRange {
+		t.Errorf("Expected: <%v>, Got: <%v>", xxd.ErrAddressRange, err)
+	}
+	cfg = xxd.NewConfig(xxd.WithFormat(xxd.DumpIntelHex), xxd.WithColumns(256))
+	var cfgErr *xxd.ConfigError
+	if err := cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "Columns" {
+		t.Errorf("Expected: <Columns error>, Got: <%v>", err)
+	}
+}
+
+func TestXxdPatchIntelHex(t *testing.T) {
+	// out of order records, one in a segment above 64 KiB
+	dump := ":0200080043446F\n" +
+		":02000200414279\n" +
+		":020000040001F9\n" +
+		":0100000045BA\n" +
+		":00000001FF\n"
+
+	f, err := os.CreateTemp(t.TempDir(), "patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("0123456789")
+	if err := xxd.XxdPatch(strings.NewReader(dump), f, xxd.NewConfig(xxd.WithFormat(xxd.DumpIntelHex))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "01AB4567CD" + strings.Repeat("\x00", 0x10000-10) + "E"
+	if string(got) != want {
+		t.Errorf("Expected: <%q>, Got: <%q>", want[:12], got[:12])
+	}
+}
+
+func TestDecoderIntelHexErrors(t *testing.T) {
+	tests := []struct {
+		dump string
+		line int
+	}{
+		{":0500000068656C6C6FE6\n", 1},
+		{":0500000068656C6CE7\n", 1},
+		{":0500000068656C6C6FE7\n0500000068656C6C6FE7\n", 2},
+		{"\n:0000000AF6\n", 2},
+		{":0500000068656C6C6GE7\n", 1},
+		// out of address order, only XxdPatch can lay these out
+		{":02000800414273\n:02000200434475\n:00000001FF\n", 2},
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpIntelHex))
+	for _, tt := range tests {
+		err := xxd.XxdReverse(strings.NewReader(tt.dump), io.Discard, cfg)
+		var decErr *xxd.DecodeError
+		if !errors.As(err, &decErr) || decErr.Line != tt.line {
+			t.Errorf("%q: Expected: <error on line %d>, Got: <%v>", tt.dump, tt.line, err)
+		}
+	}
+
+	got := &bytes.Buffer{}
+	if err := xxd.XxdPatch(strings.NewReader(":02000800414273\n:02000200434475\n:00000001FF\n"), &writerAt{got}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x00\x00CD\x00\x00\x00\x00AB"; got.String() != want {
+		t.Errorf("Expected: <%q>, Got: <%q>", want, got)
+	}
+}
+
+func TestXXDSRecord(t *testing.T) {
+	tests := []struct {
+		fname  string
+		input  string
+		stream bool // hide the input size from Xxd
+		opts   []xxd.Option
+		want   string
+	}{
+		{"hi.bin", "hi", false, nil,
+			"S009000068692E62696EBE\nS1050000686929\nS9030000FC\n"},
+		{"-", "", false, nil, "S0030000FC\nS9030000FC\n"},
+		// the image size picks S28 or S37
+		{"-", "hi", false, []xxd.Option{xxd.WithDisplayOffset(0xfffffe)},
+			"S0030000FC\nS206FFFFFE68692C\nS804000000FB\n"},
+		{"-", "hi", false, []xxd.Option{xxd.WithDisplayOffset(0x1000000), xxd.WithStartAddress(0x8000)},
+			"S0030000FC\nS30701000000686926\nS705000080007A\n"},
+		// without a size to go by every record is an S2
+		{"-", "hi!", true, []xxd.Option{xxd.WithDisplayOffset(0xfffe), xxd.WithColumns(2)},
+			"S0030000FC\nS20600FFFE68692B\nS20501000021D8\nS804000000FB\n"},
+		{"-", "hi", true, nil,
+			"S0030000FC\nS206000000686928\nS804000000FB\n"},
+		{"-", "hi", false, []xxd.Option{xxd.WithAddressWidth(3)},
+			"S0030000FC\nS206000000686928\nS804000000FB\n"},
+		// the start address widens the records too
+		{"-", "hi", false, []xxd.Option{xxd.WithStartAddress(0x123456)},
+			"S0030000FC\nS206000000686928\nS8041234565F\n"},
+		{"-", "hi", true, []xxd.Option{xxd.WithStartAddress(0x12345678)},
+			"S0030000FC\nS30700000000686927\nS70512345678E6\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(append(tt.opts, xxd.WithFormat(xxd.DumpSRecord))...)
+		var r io.Reader = strings.NewReader(tt.input)
+		if tt.stream {
+			r = iotest.OneByteReader(r)
+		}
+		dump := &bytes.Buffer{}
+		if err := xxd.Xxd(r, dump, tt.fname, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if dump.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, dump)
+		}
+
+		got := &bytes.Buffer{}
+		if err := xxd.XxdPatch(dump, &writerAt{got}, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.input {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.input, got)
+		}
+	}
+
+	// a stream past 64 KiB still has records of one type
+	big := bytes.Repeat([]byte("0123456789abcdef"), 5000)
+	dump := &bytes.Buffer{}
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpSRecord))
+	if err := xxd.Xxd(iotest.HalfReader(bytes.NewReader(big)), dump, "-", cfg); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(dump.String(), "\n"), "\n")
+	for _, l := range lines[1 : len(lines)-1] {
+		if !strings.HasPrefix(l, "S2") {
+			t.Fatalf("Expected: <S2 records only>, Got: <%s>", l)
+		}
+	}
+	if end := lines[len(lines)-1]; end != "S804000000FB" {
+		t.Errorf("Expected: <S804000000FB>, Got: <%s>", end)
+	}
+	got := &bytes.Buffer{}
+	if err := xxd.XxdPatch(dump, &writerAt{got}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), big) {
+		t.Errorf("Expected: <%d octets back>, Got: <%d>", len(big), got.Len())
+	}
+
+	// the S0 record holds as much of a long name as its count allows
+	dump.Reset()
+	if err := xxd.Xxd(strings.NewReader("hi"), dump, strings.Repeat("n", 300), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := "S0FF0000" + strings.Repeat("6E", 252); !strings.HasPrefix(dump.String(), want) {
+		t.Errorf("Expected: <%s...>, Got: <%s>", want, dump)
+	}
+	if err := xxd.XxdReverse(dump, io.Discard, cfg); err != nil {
+		t.Errorf("Expected: <a valid S0 record>, Got: <%v>", err)
+	}
+
+	cfg = xxd.NewConfig(xxd.WithFormat(xxd.DumpSRecord), xxd.WithAddressWidth(2), xxd.WithDisplayOffset(0xffff))
+	if err := xxd.Xxd(strings.NewReader("hi"), io.Discard, "-", cfg); err == nil {
+		t.Errorf("Expected: <address width error>, Got: <nil>")
+	}
+	cfg = xxd.NewConfig(xxd.WithFormat(xxd.DumpSRecord), xxd.WithDisplayOffset(0xffffff))
+	if err := xxd.Xxd(iotest.HalfReader(strings.NewReader("hi")), io.Discard, "-", cfg); err == nil {
+		t.Errorf("Expected: <address width error>, Got: <nil>")
+	}
+	cfg = xxd.NewConfig(xxd.WithFormat(xxd.DumpSRecord), xxd.WithAddressWidth(1))
+	var cfgErr *xxd.ConfigError
+	if err := cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "AddressWidth" {
+		t.Errorf("Expected: <AddressWidth error>, Got: <%v>", err)
+	}
+	cfg = xxd.NewConfig(xxd.WithFormat(xxd.DumpSRecord), xxd.WithAddressWidth(2), xxd.WithStartAddress(0x123456))
+	if err := cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "StartAddress" {
+		t.Errorf("Expected: <StartAddress error>, Got: <%v>", err)
+	}
+}
+
+func TestDecoderSRecordErrors(t *testing.T) {
+	tests := []struct {
+		dump string
+		line int
+	}{
+		{"S0030000FC\nS1050000686928\n", 2},
+		{"S10500006869\n", 1},
+		{"S4030000FC\n", 1},
+		{"S1050000686929\nS5030002FA\n", 2},
+		{"X1050000686929\n", 1},
+		{"S1050000686G29\n", 1},
+	}
+
+	cfg := xxd.NewConfig(xxd.WithFormat(xxd.DumpSRecord))
+	for _, tt := range tests {
+		err := xxd.XxdReverse(strings.NewReader(tt.dump), io.Discard, cfg)
+		var decErr *xxd.DecodeError
+		if !errors.As(err, &decErr) || decErr.Line != tt.line {
+			t.Errorf("%q: Expected: <error on line %d>, Got: <%v>", tt.dump, tt.line, err)
+		}
+	}
+
+	got := &bytes.Buffer{}
+	if err := xxd.XxdReverse(strings.NewReader("S1050000686929\nS5030001FB\nS9030000FC\n"), got, cfg); err != nil || got.String() != "hi" {
+		t.Errorf("Expected: <hi>, Got: <%q> (%v)", got, err)
+	}
+}
+
+// writerAt is an in-memory io.WriterAt
+type writerAt struct {
+	buf *bytes.Buffer
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	if n := off + int64(len(p)); n > int64(w.buf.Len()) {
+		w.buf.Write(make([]byte, n-int64(w.buf.Len())))
+	}
+	copy(w.buf.Bytes()[off:], p)
+	return len(p), nil
+}
+
+func TestXXDMemInit(t *testing.T) {
+	tests := []struct {
+		fname string
+		opts  []xxd.Option
+		want  string
+	}{
+		{"-", []xxd.Option{xxd.WithFormat(xxd.DumpReadmemh)},
+			"68 65 6c 6c 6f 2c 20 77 6f\n"},
+		{"-", []xxd.Option{xxd.WithFormat(xxd.DumpReadmemh), xxd.WithGroup(4), xxd.WithColumns(8), xxd.WithDisplayOffset(0x20)},
+			"@8\n68656c6c 6f2c2077\n6f000000\n"},
+		{"-", []xxd.Option{xxd.WithFormat(xxd.DumpReadmemh), xxd.WithGroup(2), xxd.WithLittleEndian, xxd.WithUpper},
+			"6568 6C6C 2C6F 7720 006F\n"},
+		{"-", []xxd.Option{xxd.WithFormat(xxd.DumpCoe), xxd.WithGroup(4)},
+			"memory_initialization_radix=16;\nmemory_initialization_vector=\n68656c6c,\n6f2c2077,\n6f000000;\n"},
+		{"-", []xxd.Option{xxd.WithFormat(xxd.DumpCoe), xxd.WithColumns(3)},
+			"memory_initialization_radix=16;\nmemory_initialization_vector=\n68, 65, 6c,\n6c, 6f, 2c,\n20, 77, 6f;\n"},
+		{"rom.bin", []xxd.Option{xxd.WithFormat(xxd.DumpMif), xxd.WithGroup(4)},
+			"-- rom.bin\nWIDTH=32;\nDEPTH=3;\n\nADDRESS_RADIX=HEX;\nDATA_RADIX=HEX;\n\nCONTENT BEGIN\n" +
+				"\t0 : 68656c6c;\n\t1 : 6f2c2077;\n\t2 : 6f000000;\nEND;\n"},
+		{"-", []xxd.Option{xxd.WithFormat(xxd.DumpMif), xxd.WithColumns(4), xxd.WithAddressRadix(2), xxd.WithDisplayOffset(2)},
+			"WIDTH=8;\nDEPTH=11;\n\nADDRESS_RADIX=BIN;\nDATA_RADIX=HEX;\n\nCONTENT BEGIN\n" +
+				"\t0010 : 68 65 6c 6c;\n\t0110 : 6f 2c 20 77;\n\t1010 : 6f;\nEND;\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader("hello, wo"), got, tt.fname, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, got)
+		}
+
+		// without the input size MIF waits for all of it
+		got.Reset()
+		if err := xxd.Xxd(iotest.OneByteReader(strings.NewReader("hello, wo")), got, tt.fname, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, got)
+		}
+	}
+
+	bad := []struct {
+		cfg   *xxd.Config
+		field string
+	}{
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpCoe), xxd.WithGroup(0)), "Group"},
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpMif), xxd.WithGroup(4), xxd.WithColumns(6)), "Columns"},
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpReadmemh), xxd.WithGroup(2), xxd.WithDisplayOffset(3)), "DisplayOffset"},
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpMif), xxd.WithAddressRadix(3)), "AddressRadix"},
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpMif), xxd.WithGroup(32)), ""},
+	}
+	for _, tt := range bad {
+		err := tt.cfg.Validate()
+		var cfgErr *xxd.ConfigError
+		if tt.field == "" && err != nil || tt.field != "" && (!errors.As(err, &cfgErr) || cfgErr.Field != tt.field) {
+			t.Errorf("Expected: <%s error>, Got: <%v>", tt.field, err)
+		}
+	}
+}
+
+func TestXXDNumbers(t *testing.T) {
+	in := "A\x00\x7f\x80\xff\n"
+	tests := []struct {
+		opts []xxd.Option
+		want string
+	}{
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpOctal)},
+			"00000000: 101 000 177 200 377 012" + strings.Repeat(" ", 43) + "A.....\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpDecimal), xxd.WithColumns(4)},
+			"00000000: 065 000 127 128   A...\n" +
+				"00000004: 255 010           ..\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpSigned), xxd.WithColumns(4), xxd.WithGroup(2)},
+			"00000000: +065+000 +127-128   A...\n" +
+				"00000004: -001+010            ..\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(in), got, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, got)
+		}
+
+		back := &bytes.Buffer{}
+		if err := xxd.XxdReverse(got, back, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != in {
+			t.Errorf("Expected: <%q>, Got: <%q>", in, back)
+		}
+	}
+}
+
+func TestXXDWordView(t *testing.T) {
+	in := "hello, world!"
+	tests := []struct {
+		opts []xxd.Option
+		want string
+	}{
+		{[]xxd.Option{xxd.WithWordSize(4)},
+			"00000000: 68656c6c 6f2c2077 6f726c64 21000000   hello, world!\n"},
+		{[]xxd.Option{xxd.WithWordSize(4), xxd.WithLittleEndian, xxd.WithUpper},
+			"00000000: 6C6C6568 77202C6F 646C726F 00000021   hello, world!\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpDecimal), xxd.WithWordSize(2), xxd.WithColumns(8)},
+			"00000000: 26725 27756 28460  8311   hello, w\n" +
+				"00000008: 28530 27748  8448         orld!\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpSigned), xxd.WithWordSize(4), xxd.WithLittleEndian},
+			"00000000:  1819043176  1998597231  1684828783          33   hello, world!\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpOctal), xxd.WithWordSize(8), xxd.WithColumns(8)},
+			"00000000:  641453306615713020167   hello, w\n" +
+				"00000008:  675623306204100000000   orld!\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpFloat), xxd.WithWordSize(4), xxd.WithColumns(8)},
+			"00000000:   4.3336878e+24    5.327067e+28   hello, w\n" +
+				"00000008:    7.502641e+28   4.3368087e-19   orld!\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpFloat), xxd.WithLittleEndian, xxd.WithBars},
+			"00000000:   6.518868500364834e+265       7.08582771227e-313   |hello, world!|\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(in), got, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, got)
+		}
+
+		// the short last word is cut back to the octets in the character column
+		back := &bytes.Buffer{}
+		if err := xxd.XxdReverse(got, back, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != in {
+			t.Errorf("Expected: <%q>, Got: <%q>", in, back)
+		}
+	}
+
+	bad := []struct {
+		cfg   *xxd.Config
+		field string
+	}{
+		{xxd.NewConfig(xxd.WithWordSize(3)), "WordSize"},
+		{xxd.NewConfig(xxd.WithWordSize(2), xxd.WithFormat(xxd.DumpBinary)), "WordSize"},
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpFloat), xxd.WithWordSize(2)), "WordSize"},
+		{xxd.NewConfig(xxd.WithFormat(xxd.DumpFloat), xxd.WithColumns(12)), "Columns"},
+		{xxd.NewConfig(xxd.WithWordSize(4), xxd.WithGroup(2)), "Group"},
+		{xxd.NewConfig(xxd.WithWordSize(4), xxd.WithLayout(xxd.LayoutHexdump)), "Layout"},
+	}
+	for _, tt := range bad {
+		var cfgErr *xxd.ConfigError
+		if err := tt.cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != tt.field {
+			t.Errorf("Expected: <%s error>, Got: <%v>", tt.field, err)
+		}
+	}
+}
+
+func TestXXDColor(t *testing.T) {
+	in := "a b\x00\x01\xff"
+	tests := []struct {
+		opts []xxd.Option
+		want string
+	}{
+		{[]xxd.Option{xxd.WithColor, xxd.WithColumns(8), xxd.WithGroup(1)},
+			"00000000: \x1b[1;32m61 \x1b[1;33m20 \x1b[1;32m62 \x1b[1;37m00 \x1b[1;31m01 \x1b[1;34mff \x1b[0m      " +
+				"  \x1b[1;32ma\x1b[1;33m \x1b[1;32mb\x1b[1;37m.\x1b[1;31m.\x1b[1;34m.\x1b[0m\n"},
+		{[]xxd.Option{xxd.WithColor, xxd.WithPalette(xxd.Palette{Printable: "4", High: "35"}), xxd.WithColumns(4), xxd.WithBars},
+			"00000000: \x1b[4m61\x1b[1;33m20 \x1b[4m62\x1b[1;37m00 \x1b[0m  |\x1b[4ma\x1b[1;33m \x1b[4mb\x1b[1;37m.\x1b[0m|\n" +
+				"00000004: \x1b[1;31m01\x1b[35mff \x1b[0m       |\x1b[1;31m.\x1b[35m.\x1b[0m|\n"},
+		{[]xxd.Option{xxd.WithColor, xxd.WithLayout(xxd.LayoutOd), xxd.WithColumns(4)},
+			"000000 \x1b[1;32m61 \x1b[1;33m20 \x1b[1;32m62 \x1b[1;37m00\x1b[0m  >\x1b[1;32ma\x1b[1;33m \x1b[1;32mb\x1b[1;37m.\x1b[0m<\n" +
+				"000004 \x1b[1;31m01 \x1b[1;34mff\x1b[0m        >\x1b[1;31m.\x1b[1;34m.\x1b[0m<\n" +
+				"000006\n"},
+		// words that mix classes are left plain
+		{[]xxd.Option{xxd.WithColor, xxd.WithWordSize(2), xxd.WithColumns(6)},
+			"00000000: 6120 6200 01ff   \x1b[1;32ma\x1b[1;33m \x1b[1;32mb\x1b[1;37m.\x1b[1;31m.\x1b[1;34m.\x1b[0m\n"},
+		// nothing to colour without a character column
+		{[]xxd.Option{xxd.WithColor, xxd.WithFormat(xxd.DumpPostscript)},
+			"6120620001ff\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(in), got, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.want, got)
+		}
+
+		// colours are ignored on the way back, whatever the config
+		back := &bytes.Buffer{}
+		cfg.Color = false
+		if err := xxd.XxdReverse(got, back, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != in {
+			t.Errorf("Expected: <%q>, Got: <%q>", in, back)
+		}
+	}
+
+	// byte swapped octets and words of a single class are coloured too
+	words := []struct {
+		opts []xxd.Option
+		want string
+	}{
+		{[]xxd.Option{xxd.WithColor, xxd.WithFormat(xxd.DumpLittleEndian), xxd.WithColumns(6)},
+			"00000000: \x1b[1;37m0000\x1b[1;32m6261       \x1b[1;31m01\x1b[0m   \x1b[1;32mab\x1b[1;37m..\x1b[1;31m.\x1b[0m\n"},
+		{[]xxd.Option{xxd.WithColor, xxd.WithWordSize(2), xxd.WithColumns(6)},
+			"00000000: \x1b[1;32m6162 \x1b[1;37m0000 \x1b[1;31m0100 \x1b[0m  \x1b[1;32mab\x1b[1;37m..\x1b[1;31m.\x1b[0m\n"},
+		{[]xxd.Option{xxd.WithColor, xxd.WithFormat(xxd.DumpDecimal), xxd.WithWordSize(2), xxd.WithColumns(6)},
+			"00000000: \x1b[1;32m24930     \x1b[1;37m0   \x1b[1;31m256 \x1b[0m  \x1b[1;32mab\x1b[1;37m..\x1b[1;31m.\x1b[0m\n"},
+	}
+	for _, tt := range words {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader("ab\x00\x00\x01"), got, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.want, got)
+		}
+		back := &bytes.Buffer{}
+		if err := xxd.XxdReverse(got, back, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != "ab\x00\x00\x01" {
+			t.Errorf("Expected: <%q>, Got: <%q>", "ab\x00\x00\x01", back)
+		}
+	}
+
+	p, err := xxd.ParsePalette("nul=2:high=1;35:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (xxd.Palette{Nul: "2", High: "1;35"}); p != want {
+		t.Errorf("Expected: <%+v>, Got: <%+v>", want, p)
+	}
+	for _, s := range []string{"nul=red", "blink=5"} {
+		if _, err := xxd.ParsePalette(s); err == nil {
+			t.Errorf("%s: Expected an error", s)
+		}
+	}
+
+	var cfgErr *xxd.ConfigError
+	cfg := xxd.NewConfig(xxd.WithPalette(xxd.Palette{Control: "\x1b[31m"}))
+	if err := cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "Palette" {
+		t.Errorf("Expected: <Palette error>, Got: <%v>", err)
+	}
+}
+
+func TestXXDHTML(t *testing.T) {
+	got := &bytes.Buffer{}
+	if err := xxd.XxdWith(strings.NewReader("<a>\x00"), got, "a&b.bin", xxd.WithHTML, xxd.WithColumns(2), xxd.WithGroup(1)); err != nil {
+		t.Fatal(err)
+	}
+	page := got.String()
+
+	want := `<a id="o00000000" href="#o00000000">00000000</a>: ` +
+		`<span class="p" data-o="0">3c</span> <span class="p" data-o="1">61</span>   ` +
+		`<span class="p" data-o="0">&lt;</span><span class="p" data-o="1">a</span>` + "\n" +
+		`<a id="o00000002" href="#o00000002">00000002</a>: ` +
+		`<span class="p" data-o="2">3e</span> <span class="n" data-o="3">00</span>   ` +
+		`<span class="p" data-o="2">&gt;</span><span class="n" data-o="3">.</span>` + "\n"
+	if !strings.Contains(page, "<pre>\n"+want+"</pre>\n") {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, page)
+	}
+	for _, s := range []string{"<!DOCTYPE html>\n", "<title>xxd a&amp;b.bin</title>", "<style>", "<script>", "</html>\n"} {
+		if !strings.Contains(page, s) {
+			t.Errorf("Expected: <%s>, Got: <%s>", s, page)
+		}
+	}
+	// self-contained, nothing is fetched
+	if strings.Contains(page, "src=") || strings.Contains(page, "http") {
+		t.Errorf("Expected no external assets, Got: <%s>", page)
+	}
+
+	// an empty dump is still a whole page
+	got.Reset()
+	if err := xxd.XxdWith(strings.NewReader(""), got, "-", xxd.WithHTML); err != nil {
+		t.Fatal(err)
+	}
+	if s := got.String(); !strings.HasPrefix(s, "<!DOCTYPE html>") || !strings.Contains(s, "<pre>\n</pre>") {
+		t.Errorf("Expected: <an empty page>, Got: <%s>", s)
+	}
+
+	var cfgErr *xxd.ConfigError
+	if err := xxd.NewConfig(xxd.WithHTML, xxd.WithFormat(xxd.DumpCformat)).Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "HTML" {
+		t.Errorf("Expected: <HTML error>, Got: <%v>", err)
+	}
+	if err := xxd.XxdReverse(strings.NewReader(page), io.Discard, xxd.NewConfig(xxd.WithHTML)); !errors.As(err, &cfgErr) {
+		t.Errorf("Expected: <HTML error>, Got: <%v>", err)
+	}
+}
+
+func TestXXDLayout(t *testing.T) {
+	in := "hello, world! " + strings.Repeat("\x00", 50) + "bye"
+	tests := []struct {
+		opts []xxd.Option
+		in   string
+		want string
+	}{
+		// captured from hexdump -C and od -A x -t x1z
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutHexdump)}, in,
+			"00000000  68 65 6c 6c 6f 2c 20 77  6f 72 6c 64 21 20 00 00  |hello, world! ..|\n" +
+				"00000010  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n" +
+				"*\n" +
+				"00000040  62 79 65                                          |bye|\n" +
+				"00000043\n"},
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutOd)}, in,
+			"000000 68 65 6c 6c 6f 2c 20 77 6f 72 6c 64 21 20 00 00  >hello, world! ..<\n" +
+				"000010 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00  >................<\n" +
+				"*\n" +
+				"000040 62 79 65                                         >bye<\n" +
+				"000043\n"},
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutOd), xxd.WithVerbose, xxd.WithColumns(4), xxd.WithDecimal}, "abcdabcdab",
+			"0000000 61 62 63 64  >abcd<\n" +
+				"0000004 61 62 63 64  >abcd<\n" +
+				"0000008 61 62        >ab<\n" +
+				"0000010\n"},
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutHexdump), xxd.WithColumns(6), xxd.WithGroup(3), xxd.WithUpper}, "\xfe\xedabc",
+			"00000000  FE ED 61  62 63     |..abc|\n" +
+				"00000005\n"},
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutXxd)}, "hi",
+			"00000000: 6869" + strings.Repeat(" ", 38) + "hi\n"},
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutHexdump)}, "", ""},
+		{[]xxd.Option{xxd.WithLayout(xxd.LayoutOd)}, "", "000000\n"},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(tt.in), got, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, got)
+		}
+
+		back := &bytes.Buffer{}
+		if err := xxd.XxdReverse(got, back, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != tt.in {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.in, back)
+		}
+	}
+
+	bad := []struct {
+		cfg   *xxd.Config
+		field string
+	}{
+		{xxd.NewConfig(xxd.WithLayout("hd")), "Layout"},
+		{xxd.NewConfig(xxd.WithLayout(xxd.LayoutOd), xxd.WithFormat(xxd.DumpBinary)), "Layout"},
+		{xxd.NewConfig(xxd.WithLayout(xxd.LayoutHexdump), xxd.WithCompat), "Layout"},
+		{xxd.NewConfig(xxd.WithLayout(xxd.LayoutOd), xxd.WithGroup(4)), "Group"},
+	}
+	for _, tt := range bad {
+		var cfgErr *xxd.ConfigError
+		if err := tt.cfg.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != tt.field {
+			t.Errorf("Expected: <%s error>, Got: <%v>", tt.field, err)
+		}
+	}
+}
+
+func TestDecoderLayoutRepeats(t *testing.T) {
+	// repeated lines other than nul ones are written out again
+	dump := "00000000  61 62 63 64 61 62 63 64  61 62 63 64 61 62 63 64  |abcdabcdabcdabcd|\n" +
+		"*\n" +
+		"00000030  7a                                                |z|\n" +
+		"00000031\n"
+	want := strings.Repeat("abcd", 12) + "z"
+	b, err := io.ReadAll(xxd.NewDecoder(strings.NewReader(dump), xxd.NewConfig(xxd.WithLayout(xxd.LayoutHexdump))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, b)
+	}
+
+	_, err = io.ReadAll(xxd.NewDecoder(strings.NewReader("000000 6g  >.<\n"), xxd.NewConfig(xxd.WithLayout(xxd.LayoutOd))))
+	var decErr *xxd.DecodeError
+	if !errors.As(err, &decErr) || decErr.Line != 1 {
+		t.Errorf("Expected: <line 1 error>, Got: <%v>", err)
+	}
+}
+
+func TestXXDJSON(t *testing.T) {
+	in := "say \"hi\" \\\x00\n" + strings.Repeat("\x00", 40) + "end"
+	tests := []struct {
+		opts []xxd.Option
+		in   string
+		want string
+	}{
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpNDJSON), xxd.WithColumns(8)}, "say \"hi\"\x01",
+			`{"offset":0,"hex":"7361792022686922","chars":"say \"hi\"","count":8}` + "\n" +
+				`{"offset":8,"hex":"01","chars":".","count":1}` + "\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpNDJSON), xxd.WithAutoSkip}, in,
+			`{"offset":0,"hex":"7361792022686922205c000a00000000","chars":"say \"hi\" \\......","count":16}` + "\n" +
+				`{"offset":16,"end":48,"count":32,"skip":true}` + "\n" +
+				`{"offset":48,"hex":"00000000656e64","chars":"....end","count":7}` + "\n"},
+		// a lone nul line is written as it is
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpJSON), xxd.WithAutoSkip, xxd.WithColumns(4), xxd.WithUpper}, "\xff\x00\x00\x00\x00\x00\x00\x00z",
+			"[\n" +
+				`{"offset":0,"hex":"FF000000","chars":"....","count":4},` + "\n" +
+				`{"offset":4,"hex":"00000000","chars":"....","count":4},` + "\n" +
+				`{"offset":8,"hex":"7A","chars":"z","count":1}` + "\n" +
+				"]\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpJSON), xxd.WithAutoSkip}, strings.Repeat("\x00", 48),
+			"[\n" + `{"offset":0,"end":48,"count":48,"skip":true}` + "\n]\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpJSON)}, "", "[]\n"},
+		{[]xxd.Option{xxd.WithFormat(xxd.DumpNDJSON)}, "", ""},
+	}
+
+	for _, tt := range tests {
+		cfg := xxd.NewConfig(tt.opts...)
+		got := &bytes.Buffer{}
+		if err := xxd.Xxd(strings.NewReader(tt.in), got, "-", cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Expected: <%s>, Got: <%s>", tt.want, got)
+		}
+
+		back := &bytes.Buffer{}
+		if err := xxd.XxdReverse(got, back, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != tt.in {
+			t.Errorf("Expected: <%q>, Got: <%q>", tt.in, back)
+		}
+	}
+
+	// reversing takes objects however they are laid out
+	pretty := "[\n  {\n    \"offset\": 2,\n    \"hex\": \"6869\"\n  },\n  {\"offset\": 6, \"end\": 8, \"skip\": true}\n]\n"
+	b, err := io.ReadAll(xxd.NewDecoder(strings.NewReader(pretty), xxd.NewConfig(xxd.WithFormat(xxd.DumpJSON))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x00\x00hi\x00\x00\x00\x00"; string(b) != want {
+		t.Errorf("Expected: <%q>, Got: <%q>", want, b)
+	}
+
+	bad := []struct {
+		dump string
+		line int
+	}{
+		{"{\"offset\":0,\"hex\":\"6162\",\"count\":2}\n{\"offset\":2,\"hex\":\"63\",\"count\":3}\n", 2},
+		{"[\n{\"offset\":0,\"hex\":\"6162\"},\n{\"offset\":2,\n\"hex\":\"6x\"}]", 4},
+		{"[\n{\"offset\":0,\"hex\":\"6162\"},\n{\"offset\":2 \"hex\":\"63\"}]", 3},
+		{"{\"offset\":\"0\",\"hex\":\"61\"}\n", 1},
+		{"{\"skip\":true,\"end\":4}\n", 1},
+		{"[{\"offset\":0,\"hex\":\"6162\"}", 1},
+		{"[{\"offset\":0,\"hex\":\"6162\"}]\nx", 2},
+	}
+	for _, tt := range bad {
+		_, err := io.ReadAll(xxd.NewDecoder(strings.NewReader(tt.dump), xxd.NewConfig(xxd.WithFormat(xxd.DumpJSON))))
+		var decErr *xxd.DecodeError
+		if !errors.As(err, &decErr) || decErr.Line != tt.line {
+			t.Errorf("Expected: <line %d error>, Got: <%v>", tt.line, err)
+		}
+	}
+}
+
+const compatDir = "../testdata/compat"
+
+// compatCase is one section of testdata/compat/golden.txt, see generate.sh
+type compatCase struct {
+	args string
+	want string
+}
+
+func readCompatCases(t *testing.T) []compatCase {
+	t.Helper()
+
+	b, err := os.ReadFile(compatDir + "/golden.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases []compatCase
+	for _, l := range strings.SplitAfter(string(b), "\n") {
+		if strings.HasPrefix(l, "### ") {
+			cases = append(cases, compatCase{args: strings.TrimSpace(l[4:])})
+		} else if len(cases) > 0 {
+			cases[len(cases)-1].want += l
+		}
+	}
+	if len(cases) == 0 {
+		t.Fatal("no cases in golden.txt")
+	}
+	return cases
+}
+
+// compatConfig turns xxd command line arguments into a compat Config, the
+// input file name ("-" for stdin) and whether -r was given
+func compatConfig(args string) (*xxd.Config, string, bool, error) {
+	var (
+		fs   = flag.NewFlagSet("xxd", flag.ContinueOnError)
+		cfg  = xxd.NewConfig(xxd.WithCompat)
+		ps   = fs.Bool("ps", false, "")
+		inc  = fs.Bool("i", false, "")
+		bin  = fs.Bool("b", false, "")
+		le   = fs.Bool("e", false, "")
+		rev  = fs.Bool("r", false, "")
+		seek = fs.String("s", "", "")
+		off  = fs.String("o", "", "")
+	)
+	fs.BoolVar(&cfg.AutoSkip, "a", false, "")
+	fs.BoolVar(&cfg.Ebcdic, "E", false, "")
+	fs.BoolVar(&cfg.Upper, "u", false, "")
+	fs.BoolVar(&cfg.Decimal, "d", false, "")
+	fs.IntVar(&cfg.Columns, "c", -1, "")
+	fs.IntVar(&cfg.Group, "g", -1, "")
+	fs.Int64Var(&cfg.Length, "l", -1, "")
+	fs.StringVar(&cfg.VarName, "n", "", "")
+	fs.BoolVar(&cfg.Capitalize, "C", false, "")
+	if err := fs.Parse(strings.Fields(args)); err != nil {
+		return nil, "", false, err
+	}
+
+	switch {
+	case *ps:
+		cfg.DumpType = xxd.DumpPostscript
+	case *inc:
+		cfg.DumpType = xxd.DumpCformat
+	case *bin:
+		cfg.DumpType = xxd.DumpBinary
+	case *le:
+		cfg.DumpType = xxd.DumpLittleEndian
+	}
+	cfg.Seek = *seek
+	if *off != "" {
+		v, err := xxd.ParseSize(*off)
+		if err != nil {
+			return nil, "", false, err
+		}
+		cfg.DisplayOffset = v
+	}
+
+	fname := "-"
+	if rest := fs.Args(); len(rest) == 1 {
+		fname = rest[0]
+	} else if len(rest) == 2 && rest[0] == "<" {
+		fname = "<" + rest[1]
+	}
+	return cfg, fname, *rev, nil
+}
+
+func TestDecoderCompatLittleEndian(t *testing.T) {
+	// the last group runs into the characters, as vim's does
+	cfg := xxd.NewConfig(xxd.WithCompat, xxd.WithFormat(xxd.DumpLittleEndian), xxd.WithColumns(5))
+	dump := &bytes.Buffer{}
+	if err := xxd.Xxd(strings.NewReader("hello"), dump, "-", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := "00000000: 6c6c6568     he6fo\n"; dump.String() != want {
+		t.Errorf("Expected: <%s>, Got: <%s>", want, dump)
+	}
+	err := xxd.XxdReverse(dump, io.Discard, cfg)
+	var decErr *xxd.DecodeError
+	if !errors.As(err, &decErr) || decErr.Line != 1 {
+		t.Errorf("Expected: <line 1 error>, Got: <%v>", err)
+	}
+
+	// whole groups read back as ever
+	cfg = xxd.NewConfig(xxd.WithCompat, xxd.WithFormat(xxd.DumpLittleEndian), xxd.WithColumns(8))
+	dump.Reset()
+	if err := xxd.Xxd(strings.NewReader("hello, world"), dump, "-", cfg); err != nil {
+		t.Fatal(err)
+	}
+	got := &bytes.Buffer{}
+	if err := xxd.XxdReverse(dump, got, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "hello, world" {
+		t.Errorf("Expected: <hello, world>, Got: <%q>", got)
+	}
+}
+
+func TestCompatGolden(t *testing.T) {
+	var last string // output of the last forward case
+	for _, c := range readCompatCases(t) {
+		cfg, fname, reverse, err := compatConfig(c.args)
+		if err != nil {
+			t.Fatalf("%s: %v", c.args, err)
+		}
+
+		if reverse {
+			got := &bytes.Buffer{}
+			if err := xxd.XxdReverse(strings.NewReader(last), got, cfg); err != nil {
+				t.Errorf("xxd %s: %v", c.args, err)
+				continue
+			}
+			want := strings.Join(strings.Fields(c.want), "")
+			if hex := fmt.Sprintf("%x", got.Bytes()); hex != want {
+				t.Errorf("xxd %s:\nExpected: <%s>\nGot:      <%s>", c.args, want, hex)
+			}
+			continue
+		}
+
+		// "< FILE" dumps stdin
+		name := fname
+		if strings.HasPrefix(fname, "<") {
+			fname, name = fname[1:], "-"
+		}
+		f, err := os.Open(compatDir + "/" + fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := &bytes.Buffer{}
+		err = xxd.Xxd(f, got, name, cfg)
+		f.Close()
+		if err != nil {
+			t.Errorf("xxd %s: %v", c.args, err)
+			continue
+		}
+		if got.String() != c.want {
+			t.Errorf("xxd %s:\nExpected:\n%s\nGot:\n%s", c.args, c.want, got)
+		}
+		last = c.want
+	}
+}
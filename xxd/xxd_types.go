@@ -5,10 +5,31 @@ const (
 	DumpBinary
 	DumpCformat
 	DumpPostscript
+	DumpLittleEndian
+	DumpGo
+	DumpRust
+	DumpPython
+	DumpJavaScript
+	DumpJava
+	DumpCSharp
+	DumpIntelHex
+	DumpSRecord
+	DumpReadmemh // Verilog $readmemh
+	DumpCoe      // Xilinx .coe
+	DumpMif      // Intel (Altera) .mif
+	DumpOctal    // octets as 3 octal digits, like od -t o1
+	DumpDecimal  // octets as 3 decimal digits, like od -t u1
+	DumpSigned   // octets as a sign and 3 decimal digits, like od -t d1
+	DumpFloat    // IEEE floats of Config.WordSize octets, like od -t f8
+	DumpJSON     // a JSON array of line objects
+	DumpNDJSON   // a line object per line, newline delimited JSON
 )
 
 const ebcdicOffset = 0x40
 
+// digits in an offset unless Config.OffsetWidth says otherwise
+const defaultOffsetWidth = 8
+
 // ascii -> ebcdic lookup table
 var ebcdicTable = []byte{
 	0040, 0240, 0241, 0242, 0243, 0244, 0245, 0246,
@@ -39,16 +60,16 @@ var ebcdicTable = []byte{
 
 // variables used in xxd*()
 var (
-	dumpType int
-
 	space        = []byte(" ")
 	twoSpaces    = []byte("  ")
 	doubleSpace  = []byte("  ")
 	dot          = []byte(".")
 	newLine      = []byte("\n")
-	zeroHeader   = []byte("0000000: ")
+	colonSpace   = []byte(": ")
 	unsignedChar = []byte("unsigned char ")
-	unsignedInt  = []byte("};\nunsigned int ")
+	unsignedInt  = []byte("unsigned int ")
+	closeBrace   = []byte("};\n")
+	closeBraceNl = []byte("}\n")
 	lenEquals    = []byte("_len = ")
 	brackets     = []byte("[] = {")
 	asterisk     = []byte("*")
@@ -67,18 +88,49 @@ type Config struct {
 	Ebcdic     bool
 	Group      int
 	Cfmt       bool
-	Length     int
+	Length     int64 // octets to dump or reverse, -1 for all
 	Postscript bool
 	Reverse    bool
+	FillGaps   bool // XxdPatch: write zeros between lines instead of skipping
 	Seek       string
 	Upper      bool
 	Version    bool
-}
 
-type Option func(cfg *Config)
+	DisplayOffset int64 // added to the offsets shown (-o)
+	SeekOffsets   bool  // offsets shown include the Seek position
+	Decimal       bool  // offsets shown in decimal (-d)
+	OffsetWidth   int   // minimum digits in an offset, 0 for 8
+	Compat        bool  // byte-for-byte the output of vim's xxd, see WithCompat
 
-func WithEbcdic(cfg *Config) {
-	cfg.Ebcdic = true
-}
+	// ANSI colours by class of octet, for dumps with a character column
+	Color   bool
+	Palette Palette // colours used, see DefaultPalette
+	HTML    bool    // a self-contained HTML page of the dump, see WithHTML
+
+	// hex dump layout, one of the Layout* presets, LayoutXxd if empty
+	Layout  string
+	Verbose bool // hexdump and od layouts: print repeated lines instead of a '*'
 
-//TODO:: Support other options
+	// C include output (-i)
+	VarName    string // variable name, derived from the file name if empty (-n)
+	Capitalize bool   // upper case the variable names (-C)
+	Static     bool   // declare the array and its length static
+	Const      bool   // declare the array and its length const
+	Align      int    // alignas(Align) on the array, 0 for none
+
+	// Go source output
+	Package  string // package clause, "main" if empty
+	GoString bool   // declare a const string instead of a []byte
+
+	// Intel HEX and Motorola S-record output
+	StartAddress int64 // entry point given in a start address record, -1 for none
+	AddressWidth int   // S-record address bytes, 2, 3 or 4, 0 to fit the image, see WithAddressWidth
+
+	// memory initialisation files, with words of Group octets
+	LittleEndian bool // words are stored least significant octet first
+	AddressRadix int  // MIF addresses in base 2, 8, 10 or 16, 0 for 16
+
+	// word views: hex, octal and decimal dumps show each WordSize octets
+	// as a single number, in LittleEndian order, DumpFloat as a float
+	WordSize int // 2, 4 or 8; 0 or 1 for octets, DumpFloat's 0 is 8
+}
@@ -0,0 +1,196 @@
+package xxd
+
+import (
+	"fmt"
+)
+
+// ConfigError reports a Config field holding a value that cannot be
+// dumped with. Use errors.As to get at the offending field.
+type ConfigError struct {
+	Field  string      // name of the Config field, e.g. "Columns"
+	Value  interface{} // the rejected value
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("xxd: invalid %s %v: %s", e.Field, e.Value, e.Reason)
+}
+
+// Validate checks cfg for values Xxd and XxdReverse cannot work with and
+// returns a *ConfigError describing the first one found. -1 is accepted
+// wherever it means "use the default".
+func (cfg *Config) Validate() error {
+	if !validDumpType(cfg.DumpType) {
+		return &ConfigError{"DumpType", cfg.DumpType, "unknown dump type"}
+	}
+
+	cols := cfg.Columns
+	switch {
+	case cols == -1, cols == 0 && cfg.Compat:
+		cols = defaultColumns(cfg.DumpType)
+		if isMemInit(cfg.DumpType) && cfg.Group > cols {
+			// a word per line
+			cols = cfg.Group
+		}
+	case cols < 1:
+		return &ConfigError{"Columns", cfg.Columns, "must be at least 1"}
+	}
+
+	// vim's xxd refuses more
+	if cfg.Compat && cols > 256 {
+		return &ConfigError{"Columns", cfg.Columns, "at most 256 in compat mode"}
+	}
+
+	switch {
+	case cfg.Group < -1:
+		return &ConfigError{"Group", cfg.Group, "must not be negative"}
+	case cfg.Group > cols:
+		return &ConfigError{"Group", cfg.Group, fmt.Sprintf("larger than %d columns", cols)}
+	}
+
+	if cfg.HTML && !hasOffsets(cfg.DumpType) {
+		return &ConfigError{"HTML", cfg.HTML, "only applies to dumps with a character column"}
+	}
+
+	if err := cfg.validateWords(cols); err != nil {
+		return err
+	}
+
+	switch cfg.Layout {
+	case "", LayoutXxd:
+	case LayoutHexdump, LayoutOd:
+		switch {
+		case cfg.DumpType != DumpHex:
+			return &ConfigError{"Layout", cfg.Layout, "only applies to hex dumps"}
+		case cfg.Compat:
+			return &ConfigError{"Layout", cfg.Layout, "compat mode is vim's xxd layout"}
+		case cfg.WordSize > 1:
+			return &ConfigError{"Layout", cfg.Layout, "only shows octets, not words"}
+		case cfg.Layout == LayoutOd && cfg.Group != -1:
+			return &ConfigError{"Group", cfg.Group, "od lines have no groups"}
+		}
+	default:
+		return &ConfigError{"Layout", cfg.Layout, "not xxd, hexdump-canonical or od"}
+	}
+
+	// xxd -e only swaps whole words
+	if cfg.DumpType == DumpLittleEndian && cfg.Group != -1 && (cfg.Group < 1 || cfg.Group&(cfg.Group-1) != 0) {
+		return &ConfigError{"Group", cfg.Group, "must be a power of 2 for little-endian dumps"}
+	}
+
+	// the byte count of a record is a single octet
+	if cfg.DumpType == DumpIntelHex && cols > 0xff {
+		return &ConfigError{"Columns", cfg.Columns, "at most 255 for Intel HEX"}
+	}
+
+	// the byte count of a record is a single octet, covering up to 4
+	// address octets and the checksum
+	if cfg.DumpType == DumpSRecord && cols > maxSRecordData(4) {
+		return &ConfigError{"Columns", cfg.Columns, "at most 250 for S-records"}
+	}
+
+	switch cfg.AddressWidth {
+	case 0, 2, 3, 4:
+	default:
+		return &ConfigError{"AddressWidth", cfg.AddressWidth, "must be 2, 3 or 4 bytes"}
+	}
+
+	if isMemInit(cfg.DumpType) {
+		switch {
+		case cfg.Group == 0:
+			return &ConfigError{"Group", cfg.Group, "words need at least 1 octet"}
+		case cfg.Group > 0 && cfg.Columns != -1 && cols%cfg.Group != 0:
+			return &ConfigError{"Columns", cfg.Columns, fmt.Sprintf("not a multiple of %d octet words", cfg.Group)}
+		case cfg.Group > 0 && cfg.DisplayOffset%int64(cfg.Group) != 0:
+			return &ConfigError{"DisplayOffset", cfg.DisplayOffset, fmt.Sprintf("not on a %d octet word", cfg.Group)}
+		}
+	}
+
+	if _, ok := radixNames[cfg.AddressRadix]; !ok && cfg.AddressRadix != 0 {
+		return &ConfigError{"AddressRadix", cfg.AddressRadix, "must be 2, 8, 10 or 16"}
+	}
+
+	if cfg.StartAddress < -1 || cfg.StartAddress >= maxAddress {
+		return &ConfigError{"StartAddress", cfg.StartAddress, "not a 32-bit address"}
+	}
+	if cfg.DumpType == DumpSRecord && cfg.AddressWidth != 0 && cfg.StartAddress >= 0 && srecWidth(cfg.StartAddress+1) > cfg.AddressWidth {
+		return &ConfigError{"StartAddress", cfg.StartAddress, fmt.Sprintf("does not fit in %d address bytes", cfg.AddressWidth)}
+	}
+
+	for _, sgr := range []string{cfg.Palette.Nul, cfg.Palette.Printable, cfg.Palette.Whitespace, cfg.Palette.Control, cfg.Palette.High} {
+		if !validSGR(sgr) {
+			return &ConfigError{"Palette", sgr, "not SGR parameters, e.g. 1;32"}
+		}
+	}
+
+	if cfg.Length < -1 {
+		return &ConfigError{"Length", cfg.Length, "must not be negative"}
+	}
+
+	if cfg.Align < 0 || cfg.Align&(cfg.Align-1) != 0 {
+		return &ConfigError{"Align", cfg.Align, "must be a power of 2"}
+	}
+
+	if !validPackage(cfg.Package) {
+		return &ConfigError{"Package", cfg.Package, "not a Go package name"}
+	}
+
+	if cfg.OffsetWidth < 0 {
+		return &ConfigError{"OffsetWidth", cfg.OffsetWidth, "must not be negative"}
+	}
+
+	if cfg.DisplayOffset < 0 {
+		return &ConfigError{"DisplayOffset", cfg.DisplayOffset, "must not be negative"}
+	}
+
+	if cfg.Seek != "" {
+		if _, err := ParseSize(cfg.Seek); err != nil {
+			return &ConfigError{"Seek", cfg.Seek, "not a size, offset or hex number"}
+		}
+	}
+	return nil
+}
+
+// validateWords checks the word view settings for a dump of cols octets
+// per line
+func (cfg *Config) validateWords(cols int) error {
+	switch cfg.WordSize {
+	case 0, 1, 2, 4, 8:
+	default:
+		return &ConfigError{"WordSize", cfg.WordSize, "must be 1, 2, 4 or 8 octets"}
+	}
+
+	size := cfg.WordSize
+	switch {
+	case cfg.DumpType == DumpFloat && (size == 1 || size == 2):
+		return &ConfigError{"WordSize", size, "floats are 4 or 8 octets"}
+	case cfg.DumpType == DumpFloat && size == 0:
+		size = 8
+	case size <= 1:
+		return nil
+	}
+
+	switch {
+	case cfg.DumpType != DumpHex && cfg.DumpType != DumpOctal && cfg.DumpType != DumpDecimal &&
+		cfg.DumpType != DumpSigned && cfg.DumpType != DumpFloat:
+		return &ConfigError{"WordSize", size, "only applies to hex, octal, decimal and float dumps"}
+	case cfg.Compat:
+		return &ConfigError{"WordSize", size, "compat mode shows octets"}
+	case cfg.Group != -1 && cfg.Group != size:
+		return &ConfigError{"Group", cfg.Group, fmt.Sprintf("words are %d octets", size)}
+	case cfg.Columns != -1 && cols%size != 0:
+		return &ConfigError{"Columns", cfg.Columns, fmt.Sprintf("not a multiple of %d octet words", size)}
+	}
+	return nil
+}
+
+// validDumpType reports whether t is one of the Dump* constants or a
+// registered source format
+func validDumpType(t int) bool {
+	switch t {
+	case DumpHex, DumpBinary, DumpCformat, DumpPostscript, DumpLittleEndian, DumpGo, DumpIntelHex, DumpSRecord,
+		DumpReadmemh, DumpCoe, DumpMif, DumpOctal, DumpDecimal, DumpSigned, DumpFloat, DumpJSON, DumpNDJSON:
+		return true
+	}
+	return sourceEmitter(t) != nil
+}
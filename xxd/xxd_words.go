@@ -0,0 +1,179 @@
+package xxd
+
+import (
+	"math"
+	"strconv"
+)
+
+// wordBase is the base Config.WordSize words of dumpType are shown in
+func wordBase(dumpType int) int {
+	switch dumpType {
+	case DumpHex:
+		return 16
+	case DumpOctal:
+		return 8
+	}
+	return 10
+}
+
+// wordWidth is the number of columns a word takes, enough for the longest
+// value of its size
+func (e *encoder) wordWidth() int {
+	bits := uint(e.words * 8)
+	switch e.dumpType {
+	case DumpHex:
+		return e.words * 2
+	case DumpSigned:
+		return len(strconv.FormatInt(-1<<(bits-1), 10))
+	case DumpFloat:
+		if bits == 32 {
+			return len("-1.00000005e-38")
+		}
+		return len("-2.2250738585072014e-308")
+	}
+	return len(strconv.FormatUint(math.MaxUint64>>(64-bits), wordBase(e.dumpType)))
+}
+
+// wordValue returns the word starting at b in Config.LittleEndian order.
+// A short last word is read as if the input went on with nul octets.
+func (e *encoder) wordValue(b []byte) uint64 {
+	var v uint64
+	for i := 0; i < e.words; i++ {
+		k := i
+		if e.cfg.LittleEndian {
+			k = e.words - 1 - i
+		}
+		v <<= 8
+		if k < len(b) {
+			v |= uint64(b[k])
+		}
+	}
+	return v
+}
+
+// appendWordView renders the octets in b as words of Config.WordSize
+// octets, each right aligned to wordWidth and followed by a space, e.g.
+// 1819043176 1998597231
+// With Config.Color a word takes the colour of its octets' class when
+// they all share one, and is left plain when they mix classes.
+func (e *encoder) appendWordView(dst []byte, b []byte) []byte {
+	var (
+		buf [32]byte
+		cur int
+	)
+	bits := e.words * 8
+
+	for i := 0; i < len(b); i += e.words {
+		v := e.wordValue(b[i:])
+		word := b[i:]
+		if len(word) > e.words {
+			word = word[:e.words]
+		}
+
+		var s []byte
+		switch e.dumpType {
+		case DumpHex:
+			s = buf[:e.words*2]
+			for k := len(s) - 1; k >= 0; k, v = k-1, v>>4 {
+				s[k] = e.caps[v&0x0f]
+			}
+		case DumpSigned:
+			s = strconv.AppendInt(buf[:0], int64(v<<(64-bits))>>(64-bits), 10)
+		case DumpFloat:
+			if bits == 32 {
+				s = strconv.AppendFloat(buf[:0], float64(math.Float32frombits(uint32(v))), 'g', -1, 32)
+			} else {
+				s = strconv.AppendFloat(buf[:0], math.Float64frombits(v), 'g', -1, 64)
+			}
+		default:
+			s = strconv.AppendUint(buf[:0], v, wordBase(e.dumpType))
+		}
+
+		for k := len(s); k < e.wordWidth(); k++ {
+			dst = append(dst, space...)
+		}
+		if e.color {
+			dst = e.beginClass(dst, wordClass(word), &cur)
+		}
+		dst = append(dst, s...)
+		dst = append(dst, space...)
+	}
+	return e.endColumn(dst, &cur)
+}
+
+// parseWord parses a word appendWordView rendered, ok is false when s is
+// not one
+func (e *encoder) parseWord(s string) (uint64, bool) {
+	bits := e.words * 8
+	switch e.dumpType {
+	case DumpSigned:
+		v, err := strconv.ParseInt(s, 10, bits)
+		return uint64(v) & (math.MaxUint64 >> (64 - bits)), err == nil
+	case DumpFloat:
+		f, err := strconv.ParseFloat(s, bits)
+		if bits == 32 {
+			return uint64(math.Float32bits(float32(f))), err == nil
+		}
+		return math.Float64bits(f), err == nil
+	}
+	v, err := strconv.ParseUint(s, wordBase(e.dumpType), bits)
+	return v, err == nil
+}
+
+// decodeWordView appends the octets of the words in a word view line,
+// given the text following its ':'. Words are right aligned, so they are
+// taken from the width a full line of them occupies, and the character
+// column says how much of a zero padded last word was input.
+func (e *encoder) decodeWordView(dst, line []byte) []byte {
+	if len(line) > 0 && isSpace(line[0]) {
+		line = line[1:]
+	}
+	values, chars := line, []byte(nil)
+	if w := e.lineWidth(); len(line) > w {
+		values, chars = line[:w], line[w:]
+	}
+
+	start := len(dst)
+	for len(values) > 0 {
+		i := 0
+		for i < len(values) && isSpace(values[i]) {
+			i++
+		}
+		k := i
+		for k < len(values) && !isSpace(values[k]) {
+			k++
+		}
+		if i == k {
+			break
+		}
+		v, ok := e.parseWord(string(values[i:k]))
+		if !ok {
+			break
+		}
+		values = values[k:]
+
+		for n := 0; n < e.words; n++ {
+			shift := uint(e.words-1-n) * 8
+			if e.cfg.LittleEndian {
+				shift = uint(n) * 8
+			}
+			dst = append(dst, byte(v>>shift))
+		}
+	}
+
+	// the two spaces, then a character per octet
+	for len(chars) > 0 && (chars[len(chars)-1] == '\n' || chars[len(chars)-1] == '\r') {
+		chars = chars[:len(chars)-1]
+	}
+	if len(chars) < 2 {
+		return dst
+	}
+	chars = chars[2:]
+	if e.cfg.Bars && len(chars) >= 2 {
+		chars = chars[1 : len(chars)-1]
+	}
+	if len(chars) > 0 && len(chars) < len(dst)-start {
+		dst = dst[:start+len(chars)]
+	}
+	return dst
+}